@@ -3,7 +3,11 @@ package main
 import (
 	"auth-api/internal/composites"
 	"auth-api/internal/config"
+	"auth-api/internal/midlleware"
+	"auth-api/pkg/client/mail"
 	"auth-api/pkg/client/sqlite"
+	"context"
+	"fmt"
 	"github.com/rs/cors"
 	"log"
 	"net"
@@ -18,13 +22,32 @@ func main() {
 	if err != nil {
 		log.Panicf("cannot load configuration: %v", err)
 	}
-	database, err := sqlite.NewDB(cfg.Database.DbDriver, cfg.Database.DbName)
+	database, err := sqlite.NewDB(cfg.Database.DbDriver, cfg.Database.DbName+cfg.Database.Config)
 	if err != nil {
 		log.Panicf("cannot create db: %v", err)
 	}
 	if database.Ping() != nil {
 		log.Panicf("cannot ping db: %v", err)
 	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(database, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if _, err := sqlite.Migrate(database, false); err != nil {
+		log.Panicf("cannot migrate db: %v", err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "points" {
+		mailer, err := newMailSender(cfg)
+		if err != nil {
+			log.Panicf("invalid mail configuration: %v", err)
+		}
+		if err := runPoints(database, mailer, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 	router := http.NewServeMux()
 	origin := os.Getenv("FRONTEND_ORIGIN")
 	if origin == "" {
@@ -35,21 +58,114 @@ func main() {
 		//AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"WWW-Authenticate", "Retry-After"},
 		AllowCredentials: true,
 	})
 	handlerWithCORS := c.Handler(router)
-	userComposite, err := composites.NewUserComposite(database)
+	mailer, err := newMailSender(cfg)
+	if err != nil {
+		log.Panicf("invalid mail configuration: %v", err)
+	}
+	throttleComposite, err := composites.NewThrottleComposite(database, cfg.Throttle.Driver)
+	if err != nil {
+		log.Panicf("invalid throttle configuration: %v", err)
+	}
+	referralComposite, err := composites.NewReferralComposite(database)
+	if err != nil {
+		log.Panicf("cannot create referral composite: %v", err)
+	}
+	referralComposite.Handler.Register(router)
+
+	userComposite, err := composites.NewUserComposite(database, mailer, throttleComposite.Service, referralComposite.Service)
+	if err != nil {
+		log.Panicf("cannot create user composite: %v", err)
+	}
 	userComposite.Handler.Register(router)
+	midlleware.SetRevocationList(userComposite.SessionService)
+	if err := midlleware.SetTokenPrecedence(cfg.Auth.TokenPrecedence); err != nil {
+		log.Panicf("invalid auth configuration: %v", err)
+	}
+	if err := midlleware.SetTrustedProxyHops(cfg.Listener.TrustedProxyHops); err != nil {
+		log.Panicf("invalid listener configuration: %v", err)
+	}
+
+	deviceComposite, err := composites.NewDeviceComposite(database)
+	if err != nil {
+		log.Panicf("cannot create device composite: %v", err)
+	}
+	midlleware.SetDeviceVerifier(deviceComposite.Service)
 
-	recycleBoxComposite, err := composites.NewRecycleBoxComposite(database)
+	ruleComposite, err := composites.NewRuleComposite(database)
+	if err != nil {
+		log.Panicf("cannot create rule composite: %v", err)
+	}
+	ruleComposite.Handler.Register(router)
+
+	badgeComposite, err := composites.NewBadgeComposite(database)
+	if err != nil {
+		log.Panicf("cannot create badge composite: %v", err)
+	}
+	badgeComposite.Handler.Register(router)
+
+	recycleBoxComposite, err := composites.NewRecycleBoxComposite(database, deviceComposite.Service, ruleComposite.Service, badgeComposite.Service, referralComposite.Service)
+	if err != nil {
+		log.Panicf("cannot create recycle box composite: %v", err)
+	}
 	recycleBoxComposite.Handler.Register(router)
 
+	leaderboardComposite, err := composites.NewLeaderboardComposite(database, recycleBoxComposite.Service)
 	if err != nil {
-		log.Fatal(err)
+		log.Panicf("cannot create leaderboard composite: %v", err)
 	}
+	leaderboardComposite.Handler.Register(router)
+
+	materialComposite, err := composites.NewMaterialComposite(database)
+	if err != nil {
+		log.Panicf("cannot create material composite: %v", err)
+	}
+	materialComposite.Handler.Register(router)
+
+	rewardComposite, err := composites.NewRewardComposite(database)
+	if err != nil {
+		log.Panicf("cannot create reward composite: %v", err)
+	}
+	rewardComposite.Handler.Register(router)
+
+	rbacComposite, err := composites.NewRBACComposite(database)
+	if err != nil {
+		log.Panicf("cannot create rbac composite: %v", err)
+	}
+	rbacComposite.Handler.Register(router)
+	midlleware.SetPermissionChecker(rbacComposite.Service)
+
+	pointsComposite, err := composites.NewPointsComposite(database, mailer, recycleBoxComposite.Service)
+	if err != nil {
+		log.Panicf("cannot create points composite: %v", err)
+	}
+	pointsComposite.Handler.Register(router)
+	if cfg.Points.MaintenanceInterval > 0 {
+		go pointsComposite.Service.Schedule(context.Background(), time.Duration(cfg.Points.MaintenanceInterval)*time.Hour, cfg.Points.ReconcileFix)
+	} else if _, err := pointsComposite.Service.Reconcile(context.Background(), false); err != nil {
+		log.Printf("cannot reconcile points: %v", err)
+	}
+
 	start(handlerWithCORS, cfg)
 }
 
+// newMailSender picks the mail implementation, the SMTP password is read from SMTP_PASSWORD
+func newMailSender(cfg *config.Config) (mail.Sender, error) {
+	switch cfg.Mail.Driver {
+	case "", "log":
+		return mail.NewLogSender(), nil
+	case "file":
+		return mail.NewFileSender(cfg.Mail.FilePath), nil
+	case "smtp":
+		return mail.NewSMTPSender(cfg.Mail.Host, cfg.Mail.Port, cfg.Mail.Username, os.Getenv("SMTP_PASSWORD"), cfg.Mail.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
+
 func start(router http.Handler, cfg *config.Config) {
 	log.Println("Start the application...")
 	port := os.Getenv("PORT")
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"auth-api/pkg/client/sqlite"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// runMigrate handles the "migrate" subcommand:
+//
+//	migrate up [-dry-run]
+//	migrate down [-steps N] [-dry-run]
+//	migrate status
+func runMigrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down|status [-steps N] [-dry-run]")
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "print the migrations without applying them")
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	switch args[0] {
+	case "up":
+		applied, err := sqlite.Migrate(db, *dryRun)
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			fmt.Printf("%d migration(s) pending\n", len(applied))
+		} else {
+			fmt.Printf("%d migration(s) applied\n", len(applied))
+		}
+	case "down":
+		reverted, err := sqlite.Rollback(db, *steps, *dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d migration(s) reverted\n", len(reverted))
+	case "status":
+		statuses, err := sqlite.Status(db)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if st.Modified {
+				state += " (modified)"
+			}
+			fmt.Fprintf(os.Stdout, "%04d_%s\t%s\n", st.Migration.Version, st.Migration.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"auth-api/internal/composites"
+	"auth-api/pkg/client/mail"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// runPoints handles the "points" subcommand, to run the points job from cron instead of the server:
+//
+//	points run [-fix]
+//	points reconcile [-fix]
+//
+// Both only report the balances that differ from the ledger unless -fix is given.
+func runPoints(db *sql.DB, mailer mail.Sender, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: points run|reconcile [-fix]")
+	}
+	fs := flag.NewFlagSet("points "+args[0], flag.ExitOnError)
+	fix := fs.Bool("fix", false, "reset the mismatched balances to the ledger total")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	recycleBoxComposite, err := newRecycleBoxComposite(db)
+	if err != nil {
+		return err
+	}
+	pointsComposite, err := composites.NewPointsComposite(db, mailer, recycleBoxComposite.Service)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "run":
+		report, err := pointsComposite.Service.RunMaintenance(ctx, time.Now(), *fix)
+		if err != nil {
+			return err
+		}
+		for _, m := range report.Mismatches {
+			fmt.Printf("user %d: balance %d, ledger total %d\n", m.UserID, m.Balance, m.LedgerTotal)
+		}
+		for _, e := range report.Expired {
+			fmt.Printf("expired %d point(s) of user %d\n", e.Amount, e.UserID)
+		}
+		fmt.Printf("%d session(s) settled, %d point(s) expired, %d user(s) notified\n", report.SettledSessions, report.ExpiredPoints, report.Notified)
+		if len(report.Remaining) > 0 {
+			return fmt.Errorf("%d balance(s) still differ from the ledger", len(report.Remaining))
+		}
+	case "reconcile":
+		mismatches, err := pointsComposite.Service.Reconcile(ctx, *fix)
+		if err != nil {
+			return err
+		}
+		for _, m := range mismatches {
+			fmt.Printf("user %d: balance %d, ledger total %d\n", m.UserID, m.Balance, m.LedgerTotal)
+		}
+		fmt.Printf("%d mismatch(es)\n", len(mismatches))
+	default:
+		return fmt.Errorf("unknown points command %q", args[0])
+	}
+	return nil
+}
+
+// newRecycleBoxComposite builds the recycle box service the job settles the expired deposit sessions with
+func newRecycleBoxComposite(db *sql.DB) (*composites.RecycleBoxComposite, error) {
+	deviceComposite, err := composites.NewDeviceComposite(db)
+	if err != nil {
+		return nil, err
+	}
+	ruleComposite, err := composites.NewRuleComposite(db)
+	if err != nil {
+		return nil, err
+	}
+	badgeComposite, err := composites.NewBadgeComposite(db)
+	if err != nil {
+		return nil, err
+	}
+	referralComposite, err := composites.NewReferralComposite(db)
+	if err != nil {
+		return nil, err
+	}
+	return composites.NewRecycleBoxComposite(db, deviceComposite.Service, ruleComposite.Service, badgeComposite.Service, referralComposite.Service)
+}
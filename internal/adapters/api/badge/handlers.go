@@ -0,0 +1,119 @@
+package badge
+
+import (
+	"auth-api/internal/adapters/api"
+	badgeDomain "auth-api/internal/domain/badge"
+	rbacDomain "auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	myBadgesURL    = "/me/badges"
+	adminBadgesURL = "/admin/badges"
+	adminBadgeURL  = "/admin/badges/{id}"
+	GET            = "GET "
+	POST           = "POST "
+	PUT            = "PUT "
+)
+
+type handler struct {
+	badgeService badgeDomain.ServiceBadge
+}
+
+func NewHandler(service badgeDomain.ServiceBadge) api.Handler {
+	return &handler{badgeService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	requireBadgeManage := midlleware.RequirePermission(rbacDomain.PermissionBadgeManage)
+	router.Handle(GET+myBadgesURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.UserBadges))))
+	router.Handle(GET+adminBadgesURL, midlleware.TimeoutMiddleware(requireBadgeManage(http.HandlerFunc(h.ListBadges))))
+	router.Handle(POST+adminBadgesURL, midlleware.TimeoutMiddleware(requireBadgeManage(http.HandlerFunc(h.CreateBadge))))
+	router.Handle(PUT+adminBadgeURL, midlleware.TimeoutMiddleware(requireBadgeManage(http.HandlerFunc(h.UpdateBadge))))
+}
+
+// UserBadges handles listing the badges the user earned and the progress towards the others
+func (h *handler) UserBadges(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	badges, err := h.badgeService.UserBadges(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, badges)
+}
+
+// ListBadges handles listing the badge definitions, inactive ones included (badge:manage)
+func (h *handler) ListBadges(w http.ResponseWriter, r *http.Request) {
+	badges, err := h.badgeService.ListBadges(r.Context())
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, badges)
+}
+
+// CreateBadge handles adding a badge definition (badge:manage)
+func (h *handler) CreateBadge(w http.ResponseWriter, r *http.Request) {
+	var dto = &badgeDomain.CreateBadgeDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	badge, err := h.badgeService.CreateBadge(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidBadgeError) {
+			http.Error(w, "Invalid badge", http.StatusBadRequest)
+		} else if errors.Is(err, customError.BadgeExistsError) {
+			http.Error(w, "Badge already exists", http.StatusConflict)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, badge)
+}
+
+// UpdateBadge handles changing a badge definition, "active": false retires it (badge:manage)
+func (h *handler) UpdateBadge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid badge ID", http.StatusBadRequest)
+		return
+	}
+	var dto = &badgeDomain.UpdateBadgeDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	badge, err := h.badgeService.UpdateBadge(r.Context(), id, dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Badge not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidBadgeError) {
+			http.Error(w, "Invalid badge", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, badge)
+}
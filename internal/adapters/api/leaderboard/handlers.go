@@ -0,0 +1,149 @@
+package leaderboard
+
+import (
+	"auth-api/internal/adapters/api"
+	leaderboardDomain "auth-api/internal/domain/leaderboard"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	leaderboardURL = "/leaderboard"
+	settingsURL    = "/me/leaderboard"
+	GET            = "GET "
+	PUT            = "PUT "
+)
+
+type handler struct {
+	leaderboardService leaderboardDomain.ServiceLeaderboard
+}
+
+func NewHandler(service leaderboardDomain.ServiceLeaderboard) api.Handler {
+	return &handler{leaderboardService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	router.Handle(GET+leaderboardURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.Leaderboard))))
+	router.Handle(GET+settingsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.GetSettings))))
+	router.Handle(PUT+settingsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.UpdateSettings))))
+}
+
+// Leaderboard handles ranking the users, the response holds the caller's own rank.
+// Query parameters: metric (bottles, points), period (week, month, all), box_id or lat, lng, radius (meters), limit
+func (h *handler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	dto := &leaderboardDomain.QueryDTO{
+		UserID: claims.UserID,
+		Metric: query.Get("metric"),
+		Period: query.Get("period"),
+	}
+	if v := query.Get("box_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid box_id", http.StatusBadRequest)
+			return
+		}
+		dto.BoxID = &id
+	}
+	if v := query.Get("lat"); v != "" {
+		lat, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Invalid lat", http.StatusBadRequest)
+			return
+		}
+		dto.Latitude = &lat
+	}
+	if v := query.Get("lng"); v != "" {
+		lng, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Invalid lng", http.StatusBadRequest)
+			return
+		}
+		dto.Longitude = &lng
+	}
+	var err error
+	if v := query.Get("radius"); v != "" {
+		if dto.Radius, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid radius", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	board, err := h.leaderboardService.Board(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid leaderboard parameters", http.StatusBadRequest)
+		} else if errors.Is(err, customError.InvalidLocationError) {
+			http.Error(w, "Invalid coordinates or radius", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, board)
+}
+
+// GetSettings handles showing whether the user appears by name and the handle used otherwise
+func (h *handler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	settings, err := h.leaderboardService.GetSettings(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles opting in or out of appearing by name on the leaderboards
+func (h *handler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	var dto = &leaderboardDomain.SettingsDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.leaderboardService.UpdateSettings(r.Context(), claims.UserID, dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, settings)
+}
@@ -0,0 +1,95 @@
+package material
+
+import (
+	"auth-api/internal/adapters/api"
+	materialDomain "auth-api/internal/domain/material"
+	rbacDomain "auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const (
+	listMaterialsURL  = "/materials"
+	createMaterialURL = "/admin/materials"
+	updateMaterialURL = "/admin/materials/{code}"
+	GET               = "GET "
+	POST              = "POST "
+	PUT               = "PUT "
+)
+
+type handler struct {
+	materialService materialDomain.ServiceMaterial
+}
+
+func NewHandler(service materialDomain.ServiceMaterial) api.Handler {
+	return &handler{materialService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	requireMaterialManage := midlleware.RequirePermission(rbacDomain.PermissionMaterialManage)
+	router.Handle(GET+listMaterialsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ListMaterials))))
+	router.Handle(POST+createMaterialURL, midlleware.TimeoutMiddleware(requireMaterialManage(http.HandlerFunc(h.CreateMaterial))))
+	router.Handle(PUT+updateMaterialURL, midlleware.TimeoutMiddleware(requireMaterialManage(http.HandlerFunc(h.UpdateMaterial))))
+}
+
+// ListMaterials handles listing the material types with their point values
+func (h *handler) ListMaterials(w http.ResponseWriter, r *http.Request) {
+	materials, err := h.materialService.ListMaterials(r.Context())
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, materials)
+}
+
+// CreateMaterial handles adding a material type (material:manage)
+func (h *handler) CreateMaterial(w http.ResponseWriter, r *http.Request) {
+	var dto = &materialDomain.CreateMaterialDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.materialService.CreateMaterial(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidMaterialError) {
+			http.Error(w, "Invalid material", http.StatusBadRequest)
+		} else if errors.Is(err, customError.MaterialExistsError) {
+			http.Error(w, "Material already exists", http.StatusConflict)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, m)
+}
+
+// UpdateMaterial handles changing a material type (material:manage)
+func (h *handler) UpdateMaterial(w http.ResponseWriter, r *http.Request) {
+	var dto = &materialDomain.UpdateMaterialDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.materialService.UpdateMaterial(r.Context(), r.PathValue("code"), dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Material not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidMaterialError) {
+			http.Error(w, "Invalid material", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, m)
+}
@@ -0,0 +1,126 @@
+package points
+
+import (
+	"auth-api/internal/adapters/api"
+	pointsDomain "auth-api/internal/domain/points"
+	rbacDomain "auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	historyURL     = "/me/points/history"
+	resetPointsURL = "/admin/users/{id}/points/reset"
+	dateLayout     = "2006-01-02"
+	GET            = "GET "
+	POST           = "POST "
+)
+
+type handler struct {
+	pointsService pointsDomain.ServicePoints
+}
+
+func NewHandler(service pointsDomain.ServicePoints) api.Handler {
+	return &handler{pointsService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	router.Handle(GET+historyURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.History))))
+	router.Handle(POST+resetPointsURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionUserManage)(http.HandlerFunc(h.ResetPoints))))
+}
+
+// History handles listing the awards and debits of the user.
+// Query parameters: from, to (RFC 3339 or YYYY-MM-DD, a date-only to includes the whole day),
+// period (day, week, month) for the totals, limit, offset
+func (h *handler) History(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	query := r.URL.Query()
+	dto := &pointsDomain.HistoryQueryDTO{
+		UserID: claims.UserID,
+		Period: query.Get("period"),
+	}
+	var err error
+	if dto.From, err = parseDate(query.Get("from"), false); err != nil {
+		http.Error(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if dto.To, err = parseDate(query.Get("to"), true); err != nil {
+		http.Error(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if dto.Offset, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	history, err := h.pointsService.History(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid list parameters", http.StatusBadRequest)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, history)
+}
+
+// ResetPoints handles setting the balance of a user to zero (user:manage), the response is the adjustment entry
+func (h *handler) ResetPoints(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.pointsService.ResetPoints(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, t)
+}
+
+// parseDate accepts RFC 3339 or a date, endOfDay moves a date to the start of the next day
+func parseDate(v string, endOfDay bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
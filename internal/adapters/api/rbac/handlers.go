@@ -0,0 +1,95 @@
+package rbac
+
+import (
+	"auth-api/internal/adapters/api"
+	rbacDomain "auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	listRolesURL     = "/admin/roles"
+	assignRoleURL    = "/admin/users/{id}/role"
+	roleTwoFactorURL = "/admin/roles/{name}/2fa"
+	GET              = "GET "
+	PUT              = "PUT "
+)
+
+type handler struct {
+	rbacService rbacDomain.ServiceRBAC
+}
+
+func NewHandler(service rbacDomain.ServiceRBAC) api.Handler {
+	return &handler{rbacService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	requireUserManage := midlleware.RequirePermission(rbacDomain.PermissionUserManage)
+	router.Handle(GET+listRolesURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.ListRoles))))
+	router.Handle(PUT+assignRoleURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.AssignRole))))
+	router.Handle(PUT+roleTwoFactorURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.SetRoleTwoFactor))))
+}
+
+// ListRoles handles listing the roles with their permissions (user:manage)
+func (h *handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.rbacService.ListRoles(r.Context())
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, roles)
+}
+
+// AssignRole handles changing the role of a user (user:manage)
+func (h *handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	var dto = &rbacDomain.AssignRoleDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.rbacService.AssignRole(r.Context(), id, dto); err != nil {
+		if errors.Is(err, customError.UnknownRoleError) {
+			http.Error(w, "Unknown role", http.StatusBadRequest)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Role has been changed")
+}
+
+// SetRoleTwoFactor handles making two-factor authentication mandatory for a role (user:manage)
+func (h *handler) SetRoleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var dto = &rbacDomain.RoleTwoFactorDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.rbacService.SetRoleTwoFactor(r.Context(), r.PathValue("name"), dto); err != nil {
+		if errors.Is(err, customError.UnknownRoleError) {
+			http.Error(w, "Unknown role", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Two-factor requirement has been changed")
+}
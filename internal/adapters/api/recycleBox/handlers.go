@@ -2,24 +2,39 @@ package recycleBox
 
 import (
 	"auth-api/internal/adapters/api"
+	rbacDomain "auth-api/internal/domain/rbac"
 	recycleBoxDomain "auth-api/internal/domain/recycleBox"
 	customError "auth-api/internal/error"
 	"auth-api/internal/midlleware"
 	"auth-api/internal/utils"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 const (
 	createRecycleBoxURL    = "/recyclebox"
+	listRecycleBoxURL      = "/recyclebox"
+	nearbyRecycleBoxURL    = "/recyclebox/nearby"
+	collectRecycleBoxURL   = "/recyclebox/"
+	collectSuffix          = "/collect"
+	listCollectionsURL     = "/recyclebox/{id}/collections"
 	getRecycleBoxURL       = "/recyclebox/"
 	updateRecycleBoxURL    = "/recyclebox/"
 	addBottleURL           = "/recyclebox/add-bottle/"
-	addBottleWithPointsURL = "/recyclebox/add-bottle-points/"
+	deviceCredentialsURL   = "/admin/recyclebox/{id}/credentials"
+	boxMaterialsURL        = "/admin/recyclebox/{id}/materials"
+	deviceDepositURL       = "/device/deposits"
+	deviceSessionsURL      = "/device/sessions"
+	deviceSessionBottleURL = "/device/sessions/{id}/bottles"
+	deviceSessionCloseURL  = "/device/sessions/{id}/close"
+	claimSessionURL        = "/deposit-sessions/claim"
+	getSessionURL          = "/deposit-sessions/{id}"
 	GET                    = "GET "
 	POST                   = "POST "
 	PUT                    = "PUT "
@@ -34,14 +49,28 @@ func NewHandler(service recycleBoxDomain.ServiceRecycleBox) api.Handler {
 }
 
 func (h *handler) Register(router *http.ServeMux) {
-	router.Handle(POST+createRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.AdminMiddleware(http.HandlerFunc(h.CreateRecycleBox))))
+	router.Handle(POST+createRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxCreate)(http.HandlerFunc(h.CreateRecycleBox))))
+	router.Handle(GET+listRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ListRecycleBoxes))))
+	router.Handle(GET+nearbyRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.NearbyRecycleBoxes))))
 	router.Handle(GET+getRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.GetRecycleBox))))
-	router.Handle(PUT+updateRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.UpdateRecycleBox))))
-	router.Handle(POST+addBottleURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.AddBottle))))
-	router.Handle(POST+addBottleWithPointsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.AddBottleWithPoints))))
+	router.Handle(PUT+updateRecycleBoxURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxUpdate)(http.HandlerFunc(h.UpdateRecycleBox))))
+	router.Handle(POST+addBottleURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxUpdate)(http.HandlerFunc(h.AddBottle))))
+	// POST /recyclebox/{id}/collect is matched by prefix, a {id} wildcard would conflict with the add-bottle routes;
+	// collectRoute answers 404 for any other path before the permission check
+	router.Handle(POST+collectRecycleBoxURL, collectRoute(midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxCollect)(http.HandlerFunc(h.CollectRecycleBox)))))
+	router.Handle(GET+listCollectionsURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxCollect)(http.HandlerFunc(h.ListCollections))))
+	router.Handle(PUT+boxMaterialsURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxUpdate)(http.HandlerFunc(h.SetBoxMaterials))))
+	router.Handle(POST+deviceCredentialsURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxCreate)(http.HandlerFunc(h.IssueDeviceCredentials))))
+	// Deposits are counted only when the hardware of the box signs them
+	router.Handle(POST+deviceDepositURL, midlleware.TimeoutMiddleware(midlleware.DeviceMiddleware(http.HandlerFunc(h.DeviceDeposit))))
+	router.Handle(POST+deviceSessionsURL, midlleware.TimeoutMiddleware(midlleware.DeviceMiddleware(http.HandlerFunc(h.OpenDepositSession))))
+	router.Handle(POST+deviceSessionBottleURL, midlleware.TimeoutMiddleware(midlleware.DeviceMiddleware(http.HandlerFunc(h.AddSessionBottle))))
+	router.Handle(POST+deviceSessionCloseURL, midlleware.TimeoutMiddleware(midlleware.DeviceMiddleware(http.HandlerFunc(h.CloseDepositSession))))
+	router.Handle(POST+claimSessionURL, midlleware.TimeoutMiddleware(midlleware.RequirePermission(rbacDomain.PermissionBoxDeposit)(http.HandlerFunc(h.ClaimDepositSession))))
+	router.Handle(GET+getSessionURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.GetDepositSession))))
 }
 
-// CreateRecycleBox handles creating a new recycle box (Admin only)
+// CreateRecycleBox handles creating a new recycle box (box:create)
 func (h *handler) CreateRecycleBox(w http.ResponseWriter, r *http.Request) {
 	var dtoBox = &recycleBoxDomain.CreateRecycleBoxDTO{}
 	if err := json.NewDecoder(r.Body).Decode(dtoBox); err != nil {
@@ -51,13 +80,111 @@ func (h *handler) CreateRecycleBox(w http.ResponseWriter, r *http.Request) {
 
 	box, err := h.recycleBoxService.CreateRecycleBox(r.Context(), dtoBox)
 	if err != nil {
-		http.Error(w, "Failed to create recycle box", http.StatusInternalServerError)
-		log.Println(err.Error())
+		if errors.Is(err, customError.InvalidLocationError) {
+			http.Error(w, "Invalid coordinates", http.StatusBadRequest)
+		} else if errors.Is(err, customError.InvalidMaterialError) {
+			http.Error(w, "Unknown material type", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Failed to create recycle box", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
 		return
 	}
 	utils.RenderJSON(w, http.StatusCreated, box)
 }
 
+// ListRecycleBoxes handles listing recycle boxes.
+// Query parameters: q (search in title and address), full (true/false), sort, order (asc/desc), limit, offset
+func (h *handler) ListRecycleBoxes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dto := &recycleBoxDomain.ListRecycleBoxDTO{
+		Query: query.Get("q"),
+		Sort:  query.Get("sort"),
+		Order: query.Get("order"),
+	}
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if dto.Offset, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("full"); v != "" {
+		full, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid full filter", http.StatusBadRequest)
+			return
+		}
+		dto.Full = &full
+	}
+
+	list, err := h.recycleBoxService.ListRecycleBoxes(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid list parameters", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, list)
+}
+
+// NearbyRecycleBoxes handles searching the boxes around a point.
+// Query parameters: lat, lng, radius (meters), exclude_full (true/false), limit
+func (h *handler) NearbyRecycleBoxes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dto := &recycleBoxDomain.NearbyRecycleBoxDTO{}
+	var err error
+	if dto.Latitude, err = strconv.ParseFloat(query.Get("lat"), 64); err != nil {
+		http.Error(w, "Invalid lat", http.StatusBadRequest)
+		return
+	}
+	if dto.Longitude, err = strconv.ParseFloat(query.Get("lng"), 64); err != nil {
+		http.Error(w, "Invalid lng", http.StatusBadRequest)
+		return
+	}
+	if v := query.Get("radius"); v != "" {
+		if dto.Radius, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Invalid radius", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("exclude_full"); v != "" {
+		if dto.ExcludeFull, err = strconv.ParseBool(v); err != nil {
+			http.Error(w, "Invalid exclude_full", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	boxes, err := h.recycleBoxService.NearbyRecycleBoxes(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidLocationError) {
+			http.Error(w, "Invalid coordinates or radius", http.StatusBadRequest)
+		} else if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, boxes)
+}
+
 // GetRecycleBox handles fetching a recycle box by ID
 func (h *handler) GetRecycleBox(w http.ResponseWriter, r *http.Request) {
 	id, err := getIDFromURL(r, getRecycleBoxURL)
@@ -97,6 +224,8 @@ func (h *handler) UpdateRecycleBox(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if errors.Is(err, customError.NotFoundError) {
 			http.Error(w, "Recycle box not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidLocationError) {
+			http.Error(w, "Invalid coordinates", http.StatusBadRequest)
 		} else {
 			http.Error(w, "Failed to update recycle box", http.StatusInternalServerError)
 			log.Println(err.Error())
@@ -106,7 +235,7 @@ func (h *handler) UpdateRecycleBox(w http.ResponseWriter, r *http.Request) {
 	utils.RenderJSON(w, http.StatusOK, box)
 }
 
-// AddBottle handles adding a bottle to the recycle box (User access)
+// AddBottle handles adding a bottle to the recycle box without points (box:update)
 func (h *handler) AddBottle(w http.ResponseWriter, r *http.Request) {
 	id, err := getIDFromURL(r, addBottleURL)
 	if err != nil {
@@ -120,6 +249,8 @@ func (h *handler) AddBottle(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Recycle box not found", http.StatusNotFound)
 		} else if errors.Is(err, customError.BoxFullError) {
 			http.Error(w, "Recycle box is full", http.StatusBadRequest)
+		} else if errors.Is(err, customError.MaterialNotAcceptedError) {
+			http.Error(w, "Recycle box does not accept this material", http.StatusBadRequest)
 		} else {
 			http.Error(w, "Unexpected error", http.StatusInternalServerError)
 			log.Println(err.Error())
@@ -129,27 +260,77 @@ func (h *handler) AddBottle(w http.ResponseWriter, r *http.Request) {
 	utils.RenderJSON(w, http.StatusOK, box)
 }
 
-// AddBottleWithPoints handles adding a bottle and awarding points to the user (User access)
-func (h *handler) AddBottleWithPoints(w http.ResponseWriter, r *http.Request) {
-	id, err := getIDFromURL(r, addBottleWithPointsURL)
+// SetBoxMaterials handles replacing the material types a recycle box accepts (box:update)
+func (h *handler) SetBoxMaterials(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
 	if err != nil {
 		http.Error(w, "Invalid recycle box ID", http.StatusBadRequest)
 		return
 	}
+	var dto = &recycleBoxDomain.BoxMaterialsDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		handleJSONDecodeError(w, err)
+		return
+	}
 
-	// Extract user ID from context
-	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	box, err := h.recycleBoxService.SetBoxMaterials(r.Context(), id, dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Recycle box not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidMaterialError) {
+			http.Error(w, "At least one known material type is required", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, box)
+}
+
+// IssueDeviceCredentials handles replacing the device secret of a box (box:create)
+func (h *handler) IssueDeviceCredentials(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid recycle box ID", http.StatusBadRequest)
+		return
+	}
+
+	credentials, err := h.recycleBoxService.IssueDeviceCredentials(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Recycle box not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, credentials)
+}
+
+// DeviceDeposit handles a bottle reported by the device of a box, the box is the one that signed the request.
+// The bottle is only counted, points are awarded through deposit sessions.
+func (h *handler) DeviceDeposit(w http.ResponseWriter, r *http.Request) {
+	boxID, ok := r.Context().Value("deviceBoxID").(int64)
 	if !ok {
-		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		http.Error(w, "Device authentication error", http.StatusUnauthorized)
+		return
+	}
+	var dto = &recycleBoxDomain.DeviceDepositDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil && !errors.Is(err, io.EOF) {
+		handleJSONDecodeError(w, err)
 		return
 	}
 
-	box, err := h.recycleBoxService.AddBottleWithPoints(r.Context(), id, claims.UserID)
+	box, err := h.recycleBoxService.DeviceDeposit(r.Context(), boxID, dto)
 	if err != nil {
 		if errors.Is(err, customError.NotFoundError) {
 			http.Error(w, "Recycle box not found", http.StatusNotFound)
 		} else if errors.Is(err, customError.BoxFullError) {
 			http.Error(w, "Recycle box is full", http.StatusBadRequest)
+		} else if errors.Is(err, customError.MaterialNotAcceptedError) {
+			http.Error(w, "Recycle box does not accept this material", http.StatusBadRequest)
 		} else {
 			http.Error(w, "Unexpected error", http.StatusInternalServerError)
 			log.Println(err.Error())
@@ -159,6 +340,206 @@ func (h *handler) AddBottleWithPoints(w http.ResponseWriter, r *http.Request) {
 	utils.RenderJSON(w, http.StatusOK, box)
 }
 
+// OpenDepositSession handles a box starting a deposit session, the box shows the returned code
+func (h *handler) OpenDepositSession(w http.ResponseWriter, r *http.Request) {
+	boxID, ok := r.Context().Value("deviceBoxID").(int64)
+	if !ok {
+		http.Error(w, "Device authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.recycleBoxService.OpenDepositSession(r.Context(), boxID)
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, session)
+}
+
+// ClaimDepositSession handles a user claiming the session shown by a box (box:deposit)
+func (h *handler) ClaimDepositSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	var dto = &recycleBoxDomain.ClaimDepositSessionDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		handleJSONDecodeError(w, err)
+		return
+	}
+
+	session, err := h.recycleBoxService.ClaimDepositSession(r.Context(), claims.UserID, dto)
+	if err != nil {
+		if errors.Is(err, customError.DepositSessionUnavailableError) {
+			http.Error(w, "Invalid, expired or already claimed code", http.StatusBadRequest)
+		} else if errors.Is(err, customError.UserNotVerifiedError) {
+			http.Error(w, "Verify your email to earn points", http.StatusForbidden)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, session)
+}
+
+// GetDepositSession handles the user following the progress of the session they claimed
+func (h *handler) GetDepositSession(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := h.recycleBoxService.GetDepositSession(r.Context(), id, claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Deposit session not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, session)
+}
+
+// AddSessionBottle handles a box reporting an item of the claimed session,
+// the body may name the material and defaults to a bottle when empty
+func (h *handler) AddSessionBottle(w http.ResponseWriter, r *http.Request) {
+	var dto = &recycleBoxDomain.SessionBottleDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil && !errors.Is(err, io.EOF) {
+		handleJSONDecodeError(w, err)
+		return
+	}
+	h.deviceSessionAction(w, r, func(ctx context.Context, sessionId int64, boxId int64) (*recycleBoxDomain.DepositSession, error) {
+		return h.recycleBoxService.AddSessionBottle(ctx, sessionId, boxId, dto)
+	})
+}
+
+// CloseDepositSession handles a box ending a session, the points are awarded now
+func (h *handler) CloseDepositSession(w http.ResponseWriter, r *http.Request) {
+	h.deviceSessionAction(w, r, h.recycleBoxService.CloseDepositSession)
+}
+
+func (h *handler) deviceSessionAction(w http.ResponseWriter, r *http.Request,
+	action func(ctx context.Context, sessionId int64, boxId int64) (*recycleBoxDomain.DepositSession, error)) {
+	boxID, ok := r.Context().Value("deviceBoxID").(int64)
+	if !ok {
+		http.Error(w, "Device authentication error", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	session, err := action(r.Context(), id, boxID)
+	if err != nil {
+		if errors.Is(err, customError.DepositSessionUnavailableError) || errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Deposit session is not open on this box", http.StatusConflict)
+		} else if errors.Is(err, customError.BoxFullError) {
+			http.Error(w, "Recycle box is full", http.StatusBadRequest)
+		} else if errors.Is(err, customError.MaterialNotAcceptedError) {
+			http.Error(w, "Recycle box does not accept this material", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, session)
+}
+
+// collectRoute passes on only POST /recyclebox/{id}/collect, with the ID as the "id" path value
+func collectRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, collectRecycleBoxURL), collectSuffix)
+		if !found || id == "" || strings.Contains(id, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		r.SetPathValue("id", id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CollectRecycleBox handles emptying a recycle box (box:collect)
+func (h *handler) CollectRecycleBox(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid recycle box ID", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	collection, err := h.recycleBoxService.CollectRecycleBox(r.Context(), id, claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Recycle box not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.BoxEmptyError) {
+			http.Error(w, "Recycle box is empty", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, collection)
+}
+
+// ListCollections handles fetching the collection history of a recycle box (box:collect)
+func (h *handler) ListCollections(w http.ResponseWriter, r *http.Request) {
+	dto := &recycleBoxDomain.ListCollectionsDTO{}
+	var err error
+	if dto.BoxID, err = strconv.ParseInt(r.PathValue("id"), 10, 64); err != nil {
+		http.Error(w, "Invalid recycle box ID", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if dto.Offset, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	collections, err := h.recycleBoxService.ListCollections(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Recycle box not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid list parameters", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, collections)
+}
+
 // Helper function to parse ID from URL
 func getIDFromURL(r *http.Request, baseURL string) (int64, error) {
 	idStr := r.URL.Path[len(baseURL):]
@@ -0,0 +1,50 @@
+package referral
+
+import (
+	"auth-api/internal/adapters/api"
+	referralDomain "auth-api/internal/domain/referral"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const (
+	myReferralsURL = "/me/referrals"
+	GET            = "GET "
+)
+
+type handler struct {
+	referralService referralDomain.ServiceReferral
+}
+
+func NewHandler(service referralDomain.ServiceReferral) api.Handler {
+	return &handler{referralService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	router.Handle(GET+myReferralsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.Summary))))
+}
+
+// Summary handles showing the invite code of the user and how their referrals were settled
+func (h *handler) Summary(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	summary, err := h.referralService.Summary(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "User not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, summary)
+}
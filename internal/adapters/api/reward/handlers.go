@@ -0,0 +1,209 @@
+package reward
+
+import (
+	"auth-api/internal/adapters/api"
+	rbacDomain "auth-api/internal/domain/rbac"
+	rewardDomain "auth-api/internal/domain/reward"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	listRewardsURL       = "/rewards"
+	redeemRewardURL      = "/rewards/{id}/redeem"
+	myRedemptionsURL     = "/me/redemptions"
+	adminRewardsURL      = "/admin/rewards"
+	adminRewardURL       = "/admin/rewards/{id}"
+	partnerRedemptionURL = "/partner/redemptions/{code}"
+	consumeRedemptionURL = "/partner/redemptions/{code}/consume"
+	GET                  = "GET "
+	POST                 = "POST "
+	PUT                  = "PUT "
+)
+
+type handler struct {
+	rewardService rewardDomain.ServiceReward
+}
+
+func NewHandler(service rewardDomain.ServiceReward) api.Handler {
+	return &handler{rewardService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	requireRewardManage := midlleware.RequirePermission(rbacDomain.PermissionRewardManage)
+	requireRewardConsume := midlleware.RequirePermission(rbacDomain.PermissionRewardConsume)
+	router.Handle(GET+listRewardsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ListRewards))))
+	router.Handle(POST+redeemRewardURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.RedeemReward))))
+	router.Handle(GET+myRedemptionsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ListRedemptions))))
+	router.Handle(GET+adminRewardsURL, midlleware.TimeoutMiddleware(requireRewardManage(http.HandlerFunc(h.ListAllRewards))))
+	router.Handle(POST+adminRewardsURL, midlleware.TimeoutMiddleware(requireRewardManage(http.HandlerFunc(h.CreateReward))))
+	router.Handle(PUT+adminRewardURL, midlleware.TimeoutMiddleware(requireRewardManage(http.HandlerFunc(h.UpdateReward))))
+	router.Handle(GET+partnerRedemptionURL, midlleware.TimeoutMiddleware(requireRewardConsume(http.HandlerFunc(h.ValidateRedemption))))
+	router.Handle(POST+consumeRedemptionURL, midlleware.TimeoutMiddleware(requireRewardConsume(http.HandlerFunc(h.ConsumeRedemption))))
+}
+
+// ListRewards handles listing the active rewards users can redeem
+func (h *handler) ListRewards(w http.ResponseWriter, r *http.Request) {
+	h.listRewards(w, r, false)
+}
+
+// ListAllRewards handles listing the whole catalog, inactive rewards included (reward:manage)
+func (h *handler) ListAllRewards(w http.ResponseWriter, r *http.Request) {
+	h.listRewards(w, r, true)
+}
+
+func (h *handler) listRewards(w http.ResponseWriter, r *http.Request, all bool) {
+	rewards, err := h.rewardService.ListRewards(r.Context(), all)
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, rewards)
+}
+
+// CreateReward handles adding a reward to the catalog (reward:manage)
+func (h *handler) CreateReward(w http.ResponseWriter, r *http.Request) {
+	var dto = &rewardDomain.CreateRewardDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	reward, err := h.rewardService.CreateReward(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidRewardError) {
+			http.Error(w, "Invalid reward", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, reward)
+}
+
+// UpdateReward handles changing a reward, its cost, stock or partner (reward:manage)
+func (h *handler) UpdateReward(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid reward ID", http.StatusBadRequest)
+		return
+	}
+	var dto = &rewardDomain.UpdateRewardDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	reward, err := h.rewardService.UpdateReward(r.Context(), id, dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Reward not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidRewardError) {
+			http.Error(w, "Invalid reward", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, reward)
+}
+
+// RedeemReward handles a user paying for a reward with points, the response holds the code
+func (h *handler) RedeemReward(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid reward ID", http.StatusBadRequest)
+		return
+	}
+
+	redemption, err := h.rewardService.Redeem(r.Context(), id, claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Reward not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.OutOfStockError) {
+			http.Error(w, "Reward is out of stock", http.StatusConflict)
+		} else if errors.Is(err, customError.InsufficientPointsError) {
+			http.Error(w, "Not enough points", http.StatusConflict)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, redemption)
+}
+
+// ListRedemptions handles listing the rewards the user redeemed with their codes
+func (h *handler) ListRedemptions(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	redemptions, err := h.rewardService.ListRedemptions(r.Context(), claims.UserID)
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, redemptions)
+}
+
+// ValidateRedemption handles a partner checking a code before handing out the reward (reward:consume)
+func (h *handler) ValidateRedemption(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	redemption, err := h.rewardService.ValidateRedemption(r.Context(), r.PathValue("code"), claims.UserID, claims.Role)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Redemption code not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, redemption)
+}
+
+// ConsumeRedemption handles a partner marking a code used (reward:consume)
+func (h *handler) ConsumeRedemption(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+
+	redemption, err := h.rewardService.ConsumeRedemption(r.Context(), r.PathValue("code"), claims.UserID, claims.Role)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Redemption code not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.RedemptionConsumedError) {
+			http.Error(w, "Redemption code is already used", http.StatusConflict)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, redemption)
+}
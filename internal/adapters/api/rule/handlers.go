@@ -0,0 +1,122 @@
+package rule
+
+import (
+	"auth-api/internal/adapters/api"
+	rbacDomain "auth-api/internal/domain/rbac"
+	ruleDomain "auth-api/internal/domain/rule"
+	customError "auth-api/internal/error"
+	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	listRulesURL  = "/admin/rules"
+	createRuleURL = "/admin/rules"
+	getRuleURL    = "/admin/rules/{id}"
+	updateRuleURL = "/admin/rules/{id}"
+	GET           = "GET "
+	POST          = "POST "
+	PUT           = "PUT "
+)
+
+type handler struct {
+	ruleService ruleDomain.ServiceRule
+}
+
+func NewHandler(service ruleDomain.ServiceRule) api.Handler {
+	return &handler{ruleService: service}
+}
+
+func (h *handler) Register(router *http.ServeMux) {
+	requireRuleManage := midlleware.RequirePermission(rbacDomain.PermissionRuleManage)
+	router.Handle(GET+listRulesURL, midlleware.TimeoutMiddleware(requireRuleManage(http.HandlerFunc(h.ListRules))))
+	router.Handle(POST+createRuleURL, midlleware.TimeoutMiddleware(requireRuleManage(http.HandlerFunc(h.CreateRule))))
+	router.Handle(GET+getRuleURL, midlleware.TimeoutMiddleware(requireRuleManage(http.HandlerFunc(h.GetRule))))
+	router.Handle(PUT+updateRuleURL, midlleware.TimeoutMiddleware(requireRuleManage(http.HandlerFunc(h.UpdateRule))))
+}
+
+// ListRules handles listing the point rules, inactive and past ones included (rule:manage)
+func (h *handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.ruleService.ListRules(r.Context())
+	if err != nil {
+		http.Error(w, "Unexpected error", http.StatusInternalServerError)
+		log.Println(err.Error())
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, rules)
+}
+
+// GetRule handles fetching a point rule (rule:manage)
+func (h *handler) GetRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.ruleService.GetRule(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Rule not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, rule)
+}
+
+// CreateRule handles adding a point rule (rule:manage)
+func (h *handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var dto = &ruleDomain.RuleDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.ruleService.CreateRule(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidRuleError) {
+			http.Error(w, "Invalid rule", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateRule handles replacing a point rule, "active": false ends it (rule:manage)
+func (h *handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid rule ID", http.StatusBadRequest)
+		return
+	}
+	var dto = &ruleDomain.RuleDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.ruleService.UpdateRule(r.Context(), id, dto)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Rule not found", http.StatusNotFound)
+		} else if errors.Is(err, customError.InvalidRuleError) {
+			http.Error(w, "Invalid rule", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, rule)
+}
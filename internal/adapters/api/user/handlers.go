@@ -2,6 +2,8 @@ package user
 
 import (
 	"auth-api/internal/adapters/api"
+	rbacDomain "auth-api/internal/domain/rbac"
+	throttleDomain "auth-api/internal/domain/throttle"
 	userDomain "auth-api/internal/domain/user"
 	customError "auth-api/internal/error"
 	"auth-api/internal/midlleware"
@@ -10,36 +12,82 @@ import (
 	"errors"
 	"io"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 const (
-	createUserURL   = "/register"
-	loginUserURL    = "/login"
-	userSettingsURL = "/settings"
-	GET             = "GET "
-	POST            = "POST "
-	PUT             = "PUT "
-	PATCH           = "PATCH "
-	DELETE          = "DELETE "
+	createUserURL       = "/register"
+	loginUserURL        = "/login"
+	loginTwoFactorURL   = "/login/2fa"
+	enrollTwoFactorURL  = "/2fa/enroll"
+	confirmTwoFactorURL = "/2fa/confirm"
+	disableTwoFactorURL = "/2fa/disable"
+	userSettingsURL     = "/settings"
+	meURL               = "/me"
+	confirmEmailURL     = "/settings/email/confirm"
+	forgotPassURL       = "/password/forgot"
+	resetPassURL        = "/password/reset"
+	verifyEmailURL      = "/verify"
+	resendVerifyURL     = "/verify/resend"
+	refreshTokenURL     = "/refresh"
+	logoutUserURL       = "/logout"
+	adminUsersURL       = "/admin/users"
+	adminUserURL        = "/admin/users/{id}"
+	blockUserURL        = "/admin/users/{id}/block"
+	unblockUserURL      = "/admin/users/{id}/unblock"
+	GET                 = "GET "
+	POST                = "POST "
+	PUT                 = "PUT "
+	PATCH               = "PATCH "
+	DELETE              = "DELETE "
 )
 
 type handler struct {
 	userService userDomain.ServiceUser
+	throttle    throttleDomain.ServiceThrottle
 }
 
 func (h *handler) Register(router *http.ServeMux) {
 	router.Handle(POST+createUserURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.CreateUser)))
 	router.Handle(PUT+userSettingsURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.UpdateUser))))
+	router.Handle(POST+confirmEmailURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.ConfirmEmailChange)))
+	router.Handle(POST+forgotPassURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.ForgotPassword)))
+	router.Handle(POST+resetPassURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.ResetPassword)))
+	router.Handle(GET+verifyEmailURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.VerifyEmail)))
+	router.Handle(POST+resendVerifyURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ResendVerification))))
+	router.Handle(GET+meURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.GetMe))))
 	router.Handle(POST+loginUserURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.LoginUser)))
+	router.Handle(POST+loginTwoFactorURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.LoginTwoFactor)))
+	router.Handle(POST+enrollTwoFactorURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.EnrollTwoFactor))))
+	router.Handle(POST+confirmTwoFactorURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.ConfirmTwoFactor))))
+	router.Handle(POST+disableTwoFactorURL, midlleware.TimeoutMiddleware(midlleware.AuthMiddleware(http.HandlerFunc(h.DisableTwoFactor))))
+	router.Handle(POST+refreshTokenURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.RefreshToken)))
+	router.Handle(POST+logoutUserURL, midlleware.TimeoutMiddleware(http.HandlerFunc(h.LogoutUser)))
+
+	requireUserManage := midlleware.RequirePermission(rbacDomain.PermissionUserManage)
+	router.Handle(GET+adminUsersURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.ListUsers))))
+	router.Handle(GET+adminUserURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.GetUser))))
+	router.Handle(POST+blockUserURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.BlockUser))))
+	router.Handle(POST+unblockUserURL, midlleware.TimeoutMiddleware(requireUserManage(http.HandlerFunc(h.UnblockUser))))
 }
 
-func NewHandler(service userDomain.ServiceUser) api.Handler {
-	return &handler{userService: service}
+func NewHandler(service userDomain.ServiceUser, throttle throttleDomain.ServiceThrottle) api.Handler {
+	return &handler{userService: service, throttle: throttle}
 }
 
 func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	log.Println("Creating user..")
+	ip := midlleware.ClientIP(r)
+	if h.throttled(w, r, throttleDomain.ScopeRegisterIP, ip) {
+		return
+	}
+	// Every registration is counted, successful or not
+	if err := h.throttle.Hit(r.Context(), throttleDomain.ScopeRegisterIP, ip); err != nil {
+		log.Println(err.Error())
+	}
 	var dtoUser = &userDomain.CreateUserDTO{}
 	if err := json.NewDecoder(r.Body).Decode(dtoUser); err != nil {
 		var unmarshalTypeError *json.UnmarshalTypeError
@@ -55,6 +103,7 @@ func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, io.EOF) {
 			log.Println(err.Error())
 			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
 		} else {
 			log.Println(err.Error())
 			http.Error(w, "Unexpected error", http.StatusInternalServerError)
@@ -68,54 +117,516 @@ func (h *handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, customError.BusyUpdateEmailError) {
 			http.Error(w, "Email is busy", http.StatusBadRequest)
 			return
+		} else if errors.Is(err, customError.InvalidReferralCodeError) {
+			http.Error(w, "Invalid referral code", http.StatusBadRequest)
+			return
+		} else if errors.Is(err, customError.SelfReferralError) {
+			http.Error(w, "Cannot use your own referral code", http.StatusBadRequest)
+			return
 		} else {
+			log.Println(err.Error())
 			http.Error(w, "Unexpected error", http.StatusInternalServerError)
-			log.Panic(err.Error())
 			return
 		}
 	}
 	utils.RenderJSON(w, http.StatusCreated, "User has been created")
 }
 
+// UpdateUser handles changing the settings of the authenticated user
 func (h *handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
 	var dtoUser = &userDomain.UpdateUserDTO{}
 	if err := json.NewDecoder(r.Body).Decode(dtoUser); err != nil {
-		http.Error(w, "Failed to read request body", http.StatusInternalServerError)
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
 		return
 	}
+	dtoUser.ID = claims.UserID
 	u, err := h.userService.UpdateUser(r.Context(), dtoUser)
 	if err != nil {
 		if errors.Is(err, customError.NothingToUpdateError) {
 			http.Error(w, "No fields have been changed", http.StatusBadRequest)
-			return
 		} else if errors.Is(err, customError.NotFoundError) {
 			http.Error(w, "Not found", http.StatusNotFound)
-			return
 		} else if errors.Is(err, customError.UpdateUserBadInputError) {
 			http.Error(w, "Invalid password", http.StatusBadRequest)
+		} else if errors.Is(err, customError.WrongPasswordError) {
+			http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		} else if errors.Is(err, customError.BusyUpdateEmailError) {
+			http.Error(w, "Email is busy", http.StatusBadRequest)
+		} else if errors.Is(err, customError.InvalidEmailError) {
+			http.Error(w, "Invalid email", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
 		}
 		return
 	}
 	utils.RenderJSON(w, http.StatusOK, u)
 }
 
+// ConfirmEmailChange handles applying a pending email change with the emailed token
+func (h *handler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var dto = &userDomain.ConfirmTokenDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil || dto.Token == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.ConfirmEmailChange(r.Context(), dto); err != nil {
+		if errors.Is(err, customError.InvalidConfirmationTokenError) {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		} else if errors.Is(err, customError.BusyUpdateEmailError) {
+			http.Error(w, "Email is busy", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Email has been changed")
+}
+
+// ForgotPassword handles requesting a password reset email, the answer is the same for unknown emails
+func (h *handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var dto = &userDomain.ForgotPasswordDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil || dto.Email == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.ForgotPassword(r.Context(), dto); err != nil {
+		log.Println(err.Error())
+	}
+	utils.RenderJSON(w, http.StatusAccepted, "If the email is registered, a reset token has been sent")
+}
+
+// ResetPassword handles setting a new password with the emailed token
+func (h *handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var dto = &userDomain.ResetPasswordDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil || dto.Token == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.ResetPassword(r.Context(), dto); err != nil {
+		if errors.Is(err, customError.InvalidConfirmationTokenError) {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		} else if errors.Is(err, customError.UpdateUserBadInputError) {
+			http.Error(w, "Invalid password", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Password has been changed")
+}
+
+// VerifyEmail handles the link from the verification email
+func (h *handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Token not found", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.VerifyEmail(r.Context(), token); err != nil {
+		if errors.Is(err, customError.InvalidConfirmationTokenError) {
+			http.Error(w, "Invalid or expired token", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Email has been verified")
+}
+
+// ResendVerification handles sending a new verification email to the authenticated user
+func (h *handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	if err := h.userService.ResendVerification(r.Context(), claims.UserID); err != nil {
+		if errors.Is(err, customError.AlreadyVerifiedError) {
+			http.Error(w, "Email is already verified", http.StatusBadRequest)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusAccepted, "Verification email has been sent")
+}
+
+// GetMe handles fetching the profile of the authenticated user
+func (h *handler) GetMe(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	u, err := h.userService.GetUserById(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, userDomain.NewProfileDTO(u))
+}
+
 func (h *handler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	var dtoUser = &userDomain.CreateUserDTO{}
 	if json.NewDecoder(r.Body).Decode(dtoUser) != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	token, err := h.userService.Login(r.Context(), dtoUser)
+	ip := midlleware.ClientIP(r)
+	account := strings.ToLower(strings.TrimSpace(dtoUser.Email))
+	if h.throttled(w, r, throttleDomain.ScopeLoginIP, ip) || h.throttled(w, r, throttleDomain.ScopeLoginAccount, account) {
+		return
+	}
+	token, challenge, err := h.userService.Login(r.Context(), dtoUser)
 	if err != nil {
 		if errors.Is(err, customError.LoginError) {
+			h.recordFailure(r, throttleDomain.ScopeLoginIP, ip)
+			h.recordFailure(r, throttleDomain.ScopeLoginAccount, account)
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
+		} else if errors.Is(err, customError.UserBlockedError) {
+			http.Error(w, "Account is blocked", http.StatusForbidden)
+			return
 		} else {
 			log.Println(err.Error())
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 	}
+	if challenge != nil {
+		// The password was right, the tokens are issued by /login/2fa which also clears the account counter
+		utils.RenderJSON(w, http.StatusOK, challenge)
+		return
+	}
+	h.resetFailures(r, throttleDomain.ScopeLoginAccount, account)
 	utils.SetCookie(w, token.Token)
+	utils.SetRefreshCookie(w, token.RefreshToken, token.RefreshExpiresAt)
 	utils.RenderJSON(w, http.StatusOK, token)
 }
+
+// LoginTwoFactor handles the second login step with a TOTP or recovery code
+func (h *handler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var dto = &userDomain.TwoFactorLoginDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil || dto.ChallengeToken == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	// Failed codes count against the same IP and account as failed passwords
+	ip := midlleware.ClientIP(r)
+	if h.throttled(w, r, throttleDomain.ScopeLoginIP, ip) {
+		return
+	}
+	account, err := h.userService.ChallengeAccount(r.Context(), dto.ChallengeToken)
+	if err != nil {
+		if errors.Is(err, customError.InvalidConfirmationTokenError) {
+			h.recordFailure(r, throttleDomain.ScopeLoginIP, ip)
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		} else {
+			log.Println(err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if h.throttled(w, r, throttleDomain.ScopeLoginAccount, account) {
+		return
+	}
+	token, err := h.userService.LoginTwoFactor(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidConfirmationTokenError) {
+			h.recordFailure(r, throttleDomain.ScopeLoginIP, ip)
+			http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		} else if errors.Is(err, customError.TwoFactorCodeError) {
+			h.recordFailure(r, throttleDomain.ScopeLoginIP, ip)
+			h.recordFailure(r, throttleDomain.ScopeLoginAccount, account)
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+		} else if errors.Is(err, customError.UserBlockedError) {
+			http.Error(w, "Account is blocked", http.StatusForbidden)
+		} else {
+			log.Println(err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	h.resetFailures(r, throttleDomain.ScopeLoginAccount, account)
+	utils.SetCookie(w, token.Token)
+	utils.SetRefreshCookie(w, token.RefreshToken, token.RefreshExpiresAt)
+	utils.RenderJSON(w, http.StatusOK, token)
+}
+
+// EnrollTwoFactor handles generating a TOTP secret for the authenticated user
+func (h *handler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	enrollment, err := h.userService.EnrollTwoFactor(r.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, customError.TwoFactorEnabledError) {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor handles enabling two-factor authentication with the first code
+func (h *handler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	var dto = &userDomain.TwoFactorCodeDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil || dto.Code == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	codes, err := h.userService.ConfirmTwoFactor(r.Context(), claims.UserID, dto)
+	if err != nil {
+		if errors.Is(err, customError.TwoFactorEnabledError) {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		} else if errors.Is(err, customError.TwoFactorNotEnabledError) {
+			http.Error(w, "Start the enrollment first", http.StatusBadRequest)
+		} else if errors.Is(err, customError.TwoFactorCodeError) {
+			http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, codes)
+}
+
+// DisableTwoFactor handles turning two-factor authentication off
+func (h *handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	var dto = &userDomain.DisableTwoFactorDTO{}
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if err := h.userService.DisableTwoFactor(r.Context(), claims.UserID, dto); err != nil {
+		if errors.Is(err, customError.WrongPasswordError) {
+			http.Error(w, "Current password is incorrect", http.StatusForbidden)
+		} else if errors.Is(err, customError.TwoFactorNotEnabledError) {
+			http.Error(w, "Two-factor authentication is not enabled", http.StatusBadRequest)
+		} else if errors.Is(err, customError.TwoFactorCodeError) {
+			http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+		} else if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, "Two-factor authentication has been disabled")
+}
+
+func (h *handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	refreshToken := readRefreshToken(r)
+	if refreshToken == "" {
+		http.Error(w, "Refresh token not found", http.StatusUnauthorized)
+		return
+	}
+	token, err := h.userService.Refresh(r.Context(), refreshToken)
+	if err != nil {
+		if errors.Is(err, customError.InvalidRefreshTokenError) || errors.Is(err, customError.RefreshTokenReuseError) {
+			utils.ClearCookies(w)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		} else if errors.Is(err, customError.UserBlockedError) {
+			utils.ClearCookies(w)
+			http.Error(w, "Account is blocked", http.StatusForbidden)
+			return
+		} else {
+			log.Println(err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	utils.SetCookie(w, token.Token)
+	utils.SetRefreshCookie(w, token.RefreshToken, token.RefreshExpiresAt)
+	utils.RenderJSON(w, http.StatusOK, token)
+}
+
+func (h *handler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+	refreshToken := readRefreshToken(r)
+	if refreshToken != "" {
+		err := h.userService.Logout(r.Context(), refreshToken)
+		if err != nil && !errors.Is(err, customError.InvalidRefreshTokenError) {
+			log.Println(err.Error())
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+	utils.ClearCookies(w)
+	utils.RenderJSON(w, http.StatusOK, "Logged out")
+}
+
+// ListUsers handles listing users (user:manage).
+// Query parameters: q (search in email and username), limit, offset
+func (h *handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	dto := &userDomain.ListUsersDTO{Query: query.Get("q")}
+	var err error
+	if v := query.Get("limit"); v != "" {
+		if dto.Limit, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if dto.Offset, err = strconv.ParseInt(v, 10, 64); err != nil {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+
+	list, err := h.userService.ListUsers(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, customError.InvalidListQueryError) {
+			http.Error(w, "Invalid list parameters", http.StatusBadRequest)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, list)
+}
+
+// GetUser handles fetching a user by ID (user:manage)
+func (h *handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	u, err := h.userService.GetUserById(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	utils.RenderJSON(w, http.StatusOK, userDomain.NewAdminUserDTO(u))
+}
+
+// BlockUser handles blocking an account (user:manage)
+func (h *handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserBlocked(w, r, true)
+}
+
+// UnblockUser handles unblocking an account (user:manage)
+func (h *handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserBlocked(w, r, false)
+}
+
+func (h *handler) setUserBlocked(w http.ResponseWriter, r *http.Request, blocked bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	claims, ok := r.Context().Value("userClaims").(*midlleware.Claims)
+	if !ok {
+		http.Error(w, "User authentication error", http.StatusUnauthorized)
+		return
+	}
+	if blocked && claims.UserID == id {
+		http.Error(w, "You cannot block yourself", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.SetUserBlocked(r.Context(), id, blocked); err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			http.Error(w, "Not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Unexpected error", http.StatusInternalServerError)
+			log.Println(err.Error())
+		}
+		return
+	}
+	if blocked {
+		utils.RenderJSON(w, http.StatusOK, "User has been blocked")
+	} else {
+		utils.RenderJSON(w, http.StatusOK, "User has been unblocked")
+	}
+}
+
+// readRefreshToken takes the refresh token from the JSON body, falling back to the refresh_token cookie
+func readRefreshToken(r *http.Request) string {
+	var dto = &userDomain.RefreshTokenDTO{}
+	if json.NewDecoder(r.Body).Decode(dto) == nil && dto.RefreshToken != "" {
+		return dto.RefreshToken
+	}
+	cookie, err := r.Cookie("refresh_token")
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// throttled answers 429 with Retry-After when the key is locked in the scope
+func (h *handler) throttled(w http.ResponseWriter, r *http.Request, scope, key string) bool {
+	retryAfter, err := h.throttle.Allow(r.Context(), scope, key)
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, customError.TooManyAttemptsError) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return true
+	}
+	log.Println(err.Error())
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+	return true
+}
+
+// resetFailures clears the counter of the key once a login is complete, failures are only logged
+func (h *handler) resetFailures(r *http.Request, scope, key string) {
+	if err := h.throttle.Reset(r.Context(), scope, key); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+// recordFailure counts a failed attempt, the request itself is answered as usual
+func (h *handler) recordFailure(r *http.Request, scope, key string) {
+	if err := h.throttle.Fail(r.Context(), scope, key); err != nil {
+		log.Println(err.Error())
+	}
+}
@@ -0,0 +1,182 @@
+package badge
+
+import (
+	adaptersPoints "auth-api/internal/adapters/db/points"
+	"auth-api/internal/domain/badge"
+	domainPoints "auth-api/internal/domain/points"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+const (
+	badgeColumns = `id, code, name, description, kind, threshold, bonus_points, active, created_at`
+	dayLayout    = "2006-01-02"
+)
+
+type storageBadge struct {
+	db *sql.DB
+}
+
+func NewBadgeStorage(db *sql.DB) badge.BadgeStorage {
+	return &storageBadge{
+		db: db,
+	}
+}
+
+func (s *storageBadge) ListBadges(activeOnly bool) ([]*badge.Badge, error) {
+	q := `SELECT ` + badgeColumns + ` FROM badges`
+	if activeOnly {
+		q += ` WHERE active = 1`
+	}
+	rows, err := s.db.Query(q + ` ORDER BY kind, threshold, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var badges []*badge.Badge
+	for rows.Next() {
+		b := &badge.Badge{}
+		if err := scanBadge(rows, b); err != nil {
+			return nil, err
+		}
+		badges = append(badges, b)
+	}
+	return badges, rows.Err()
+}
+
+func (s *storageBadge) GetBadge(id int64) (*badge.Badge, error) {
+	b := &badge.Badge{}
+	if err := scanBadge(s.db.QueryRow(`SELECT `+badgeColumns+` FROM badges WHERE id = ?`, id), b); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *storageBadge) CreateBadge(b *badge.Badge) error {
+	q := `INSERT INTO badges(code, name, description, kind, threshold, bonus_points, active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(q, b.Code, b.Name, b.Description, b.Kind, b.Threshold, b.BonusPoints, b.Active, b.CreatedAt)
+	if err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return customError.BadgeExistsError
+		}
+		return err
+	}
+	b.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *storageBadge) UpdateBadge(b *badge.Badge) error {
+	q := `UPDATE badges SET name = ?, description = ?, threshold = ?, bonus_points = ?, active = ? WHERE id = ?`
+	result, err := s.db.Exec(q, b.Name, b.Description, b.Threshold, b.BonusPoints, b.Active, b.ID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (s *storageBadge) EarnedBadges(userID int64) (map[int64]time.Time, error) {
+	rows, err := s.db.Query(`SELECT badge_id, awarded_at FROM user_badges WHERE user_id = ?`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	earned := map[int64]time.Time{}
+	for rows.Next() {
+		var id int64
+		var at time.Time
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		earned[id] = at
+	}
+	return earned, rows.Err()
+}
+
+func (s *storageBadge) DepositStats(userID int64) (int64, int64, error) {
+	var bottles, boxes int64
+	q := `SELECT COUNT(*), COUNT(DISTINCT box_id) FROM deposits WHERE user_id = ?`
+	err := s.db.QueryRow(q, userID).Scan(&bottles, &boxes)
+	return bottles, boxes, err
+}
+
+func (s *storageBadge) DepositDays(userID int64, limit int64) ([]time.Time, error) {
+	q := `SELECT DISTINCT date(created_at) AS day FROM deposits WHERE user_id = ? ORDER BY day DESC LIMIT ?`
+	rows, err := s.db.Query(q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var days []time.Time
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(dayLayout, day)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, t)
+	}
+	return days, rows.Err()
+}
+
+func (s *storageBadge) AwardBadge(userID int64, b *badge.Badge, now time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	q := `INSERT INTO user_badges(user_id, badge_id, awarded_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+	result, err := tx.Exec(q, userID, b.ID, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+
+	if b.BonusPoints > 0 {
+		t := &domainPoints.Transaction{
+			UserID:    userID,
+			Amount:    b.BonusPoints,
+			Reason:    domainPoints.ReasonBadge,
+			CreatedAt: now,
+		}
+		if err := adaptersPoints.InsertTransaction(tx, t); err != nil {
+			return false, err
+		}
+		qTransaction := `UPDATE user_badges SET transaction_id = ? WHERE user_id = ? AND badge_id = ?`
+		if _, err := tx.Exec(qTransaction, t.ID, userID, b.ID); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBadge(row rowScanner, b *badge.Badge) error {
+	return row.Scan(&b.ID, &b.Code, &b.Name, &b.Description, &b.Kind, &b.Threshold, &b.BonusPoints, &b.Active, &b.CreatedAt)
+}
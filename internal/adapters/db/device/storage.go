@@ -0,0 +1,56 @@
+package device
+
+import (
+	"auth-api/internal/domain/device"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+type storageDevice struct {
+	db *sql.DB
+}
+
+func NewDeviceStorage(db *sql.DB) device.DeviceStorage {
+	return &storageDevice{
+		db: db,
+	}
+}
+
+func (s *storageDevice) SaveDevice(d *device.Device) error {
+	q := `INSERT INTO box_devices(box_id, secret, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(box_id) DO UPDATE SET secret = excluded.secret, created_at = excluded.created_at`
+	_, err := s.db.Exec(q, d.BoxID, d.Secret, d.CreatedAt)
+	return err
+}
+
+func (s *storageDevice) GetDevice(boxID int64) (*device.Device, error) {
+	d := &device.Device{}
+	q := `SELECT box_id, secret, created_at FROM box_devices WHERE box_id = ?`
+	if err := s.db.QueryRow(q, boxID).Scan(&d.BoxID, &d.Secret, &d.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *storageDevice) UseNonce(boxID int64, nonce string, expiresAt time.Time) error {
+	q := `INSERT INTO device_nonces(box_id, nonce, expires_at) VALUES (?, ?, ?)`
+	if _, err := s.db.Exec(q, boxID, nonce, expiresAt); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return customError.DeviceReplayError
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *storageDevice) DeleteExpiredNonces(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM device_nonces WHERE expires_at < ?`, before)
+	return err
+}
@@ -0,0 +1,118 @@
+package leaderboard
+
+import (
+	"auth-api/internal/domain/leaderboard"
+	"auth-api/internal/domain/points"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+type storageLeaderboard struct {
+	db *sql.DB
+}
+
+func NewLeaderboardStorage(db *sql.DB) leaderboard.LeaderboardStorage {
+	return &storageLeaderboard{
+		db: db,
+	}
+}
+
+func (s *storageLeaderboard) Top(dto *leaderboard.QueryDTO) ([]*leaderboard.Entry, error) {
+	q, args := rankedQuery(dto)
+	rows, err := s.db.Query(q+` SELECT rank, name, value, user_id FROM ranked ORDER BY rank, user_id LIMIT ?`,
+		append(args, dto.Limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var entries []*leaderboard.Entry
+	for rows.Next() {
+		e := &leaderboard.Entry{}
+		var userID int64
+		if err := rows.Scan(&e.Rank, &e.Name, &e.Value, &userID); err != nil {
+			return nil, err
+		}
+		e.You = userID == dto.UserID
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (s *storageLeaderboard) Rank(dto *leaderboard.QueryDTO) (*leaderboard.Entry, error) {
+	q, args := rankedQuery(dto)
+	e := &leaderboard.Entry{You: true}
+	err := s.db.QueryRow(q+` SELECT rank, name, value FROM ranked WHERE user_id = ?`, append(args, dto.UserID)...).
+		Scan(&e.Rank, &e.Name, &e.Value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *storageLeaderboard) GetSettings(userID int64) (*leaderboard.Settings, error) {
+	st := &leaderboard.Settings{}
+	q := `SELECT leaderboard_public, COALESCE(handle, '') FROM users WHERE user_id = ?`
+	if err := s.db.QueryRow(q, userID).Scan(&st.Public, &st.Handle); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return st, nil
+}
+
+func (s *storageLeaderboard) SetPublic(userID int64, public bool) error {
+	result, err := s.db.Exec(`UPDATE users SET leaderboard_public = ? WHERE user_id = ?`, public, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+// rankedQuery builds the WITH clause of the ranked board. Bottles are counted from the deposit
+// history and points summed from the deposit entries of the ledger, so campaign extras count too.
+// Blocked users are left out, users who opted out are shown by their handle.
+func rankedQuery(dto *leaderboard.QueryDTO) (string, []interface{}) {
+	var totals string
+	var args []interface{}
+	if dto.Metric == leaderboard.MetricPoints {
+		totals = `SELECT user_id, SUM(amount) AS value FROM point_transactions WHERE reason = ?`
+		args = append(args, points.ReasonDeposit)
+	} else {
+		totals = `SELECT user_id, COUNT(*) AS value FROM deposits WHERE user_id IS NOT NULL`
+	}
+	if dto.Since != nil {
+		totals += ` AND created_at >= ?`
+		args = append(args, dto.Since.UTC())
+	}
+	if dto.BoxIDs != nil {
+		totals += ` AND box_id IN (?` + strings.Repeat(`, ?`, len(dto.BoxIDs)-1) + `)`
+		for _, id := range dto.BoxIDs {
+			args = append(args, id)
+		}
+	}
+	totals += ` GROUP BY user_id HAVING value > 0`
+
+	q := `WITH totals AS (` + totals + `),
+ranked AS (
+	SELECT u.user_id,
+		CASE WHEN u.leaderboard_public = 1 AND u.username <> '' THEN u.username ELSE COALESCE(u.handle, '') END AS name,
+		t.value,
+		RANK() OVER (ORDER BY t.value DESC) AS rank
+	FROM totals t JOIN users u ON u.user_id = t.user_id
+	WHERE u.blocked = 0
+)`
+	return q, args
+}
@@ -0,0 +1,74 @@
+package material
+
+import (
+	"auth-api/internal/domain/material"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+)
+
+type storageMaterial struct {
+	db *sql.DB
+}
+
+func NewMaterialStorage(db *sql.DB) material.MaterialStorage {
+	return &storageMaterial{
+		db: db,
+	}
+}
+
+func (s *storageMaterial) ListMaterials() ([]*material.Material, error) {
+	rows, err := s.db.Query(`SELECT code, name, points, weight, active FROM materials ORDER BY code`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var materials []*material.Material
+	for rows.Next() {
+		m := &material.Material{}
+		if err := rows.Scan(&m.Code, &m.Name, &m.Points, &m.Weight, &m.Active); err != nil {
+			return nil, err
+		}
+		materials = append(materials, m)
+	}
+	return materials, rows.Err()
+}
+
+func (s *storageMaterial) GetMaterial(code string) (*material.Material, error) {
+	m := &material.Material{}
+	q := `SELECT code, name, points, weight, active FROM materials WHERE code = ?`
+	if err := s.db.QueryRow(q, code).Scan(&m.Code, &m.Name, &m.Points, &m.Weight, &m.Active); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *storageMaterial) CreateMaterial(m *material.Material) error {
+	q := `INSERT INTO materials(code, name, points, weight, active) VALUES (?, ?, ?, ?, ?)`
+	if _, err := s.db.Exec(q, m.Code, m.Name, m.Points, m.Weight, m.Active); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return customError.MaterialExistsError
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *storageMaterial) UpdateMaterial(m *material.Material) error {
+	q := `UPDATE materials SET name = ?, points = ?, weight = ?, active = ? WHERE code = ?`
+	result, err := s.db.Exec(q, m.Name, m.Points, m.Weight, m.Active, m.Code)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package points
+
+import (
+	"auth-api/internal/domain/points"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// periodExpressions group created_at by day, by week starting on Monday and by month
+var periodExpressions = map[string]string{
+	points.PeriodDay:   `date(pt.created_at)`,
+	points.PeriodWeek:  `date(pt.created_at, '-6 days', 'weekday 1')`,
+	points.PeriodMonth: `strftime('%Y-%m', pt.created_at)`,
+}
+
+type storagePoints struct {
+	db *sql.DB
+}
+
+func NewPointsStorage(db *sql.DB) points.PointsStorage {
+	return &storagePoints{
+		db: db,
+	}
+}
+
+// InsertTransaction writes a ledger entry and applies it to users.points inside the caller's transaction
+func InsertTransaction(tx *sql.Tx, t *points.Transaction) error {
+	if err := insertLedgerEntry(tx, t); err != nil {
+		return err
+	}
+	qBalance := `UPDATE users SET points = points + ? WHERE user_id = ?`
+	result, err := tx.Exec(qBalance, t.Amount, t.UserID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+// DebitTransaction writes a debit (a negative amount) only if the balance covers it.
+// The balance is checked and changed by one statement, so concurrent debits cannot overspend.
+func DebitTransaction(tx *sql.Tx, t *points.Transaction) error {
+	qBalance := `UPDATE users SET points = points + ? WHERE user_id = ? AND points + ? >= 0`
+	result, err := tx.Exec(qBalance, t.Amount, t.UserID, t.Amount)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		var exists int
+		if err := tx.QueryRow(`SELECT 1 FROM users WHERE user_id = ?`, t.UserID).Scan(&exists); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return customError.NotFoundError
+			}
+			return err
+		}
+		return customError.InsufficientPointsError
+	}
+	return insertLedgerEntry(tx, t)
+}
+
+func insertLedgerEntry(tx *sql.Tx, t *points.Transaction) error {
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now().UTC()
+	}
+	q := `INSERT INTO point_transactions(user_id, box_id, amount, reason, created_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := tx.Exec(q, t.UserID, t.BoxID, t.Amount, t.Reason, t.CreatedAt)
+	if err != nil {
+		return err
+	}
+	t.ID, err = result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	for _, r := range t.Rules {
+		qRule := `INSERT INTO point_transaction_rules(transaction_id, rule_id, points) VALUES (?, ?, ?)`
+		if _, err := tx.Exec(qRule, t.ID, r.RuleID, r.Points); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *storagePoints) FindMismatches() ([]*points.Mismatch, error) {
+	q := `
+SELECT u.user_id, u.points, COALESCE(SUM(pt.amount), 0) AS total
+FROM users u
+LEFT JOIN point_transactions pt ON pt.user_id = u.user_id
+GROUP BY u.user_id, u.points
+HAVING u.points <> total`
+	rows, err := s.db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var mismatches []*points.Mismatch
+	for rows.Next() {
+		m := &points.Mismatch{}
+		if err := rows.Scan(&m.UserID, &m.Balance, &m.LedgerTotal); err != nil {
+			return nil, err
+		}
+		mismatches = append(mismatches, m)
+	}
+	return mismatches, rows.Err()
+}
+
+// FixBalance derives users.points from the ledger
+func (s *storagePoints) FixBalance(userID int64) error {
+	q := `UPDATE users SET points = (SELECT COALESCE(SUM(amount), 0) FROM point_transactions WHERE user_id = ?) WHERE user_id = ?`
+	_, err := s.db.Exec(q, userID, userID)
+	return err
+}
+
+func (s *storagePoints) GetBalance(userID int64) (int64, error) {
+	var balance int64
+	if err := s.db.QueryRow(`SELECT points FROM users WHERE user_id = ?`, userID).Scan(&balance); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, customError.NotFoundError
+		}
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (s *storagePoints) ListTransactions(dto *points.HistoryQueryDTO) ([]*points.HistoryEntry, int64, error) {
+	whereClause, args := historyFilter(dto)
+
+	var total int64
+	qCount := `SELECT COUNT(*) FROM point_transactions pt` + whereClause
+	if err := s.db.QueryRow(qCount, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT pt.id, pt.user_id, pt.box_id, pt.amount, pt.reason, pt.created_at, rb.title
+FROM point_transactions pt LEFT JOIN recycle_boxes rb ON rb.id = pt.box_id` + whereClause + `
+ORDER BY pt.created_at DESC, pt.id DESC LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(q, append(args, dto.Limit, dto.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var items []*points.HistoryEntry
+	for rows.Next() {
+		t := &points.Transaction{}
+		var boxID sql.NullInt64
+		var boxTitle sql.NullString
+		if err := rows.Scan(&t.ID, &t.UserID, &boxID, &t.Amount, &t.Reason, &t.CreatedAt, &boxTitle); err != nil {
+			return nil, 0, err
+		}
+		item := &points.HistoryEntry{Transaction: t}
+		if boxID.Valid {
+			t.BoxID = &boxID.Int64
+		}
+		if boxTitle.Valid {
+			item.BoxTitle = &boxTitle.String
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+func (s *storagePoints) TransactionRules(ids []int64) (map[int64][]*points.TransactionRule, error) {
+	rules := make(map[int64][]*points.TransactionRule)
+	if len(ids) == 0 {
+		return rules, nil
+	}
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	q := `SELECT transaction_id, rule_id, points FROM point_transaction_rules
+WHERE transaction_id IN (?` + strings.Repeat(", ?", len(ids)-1) + `) ORDER BY rule_id`
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transactionID int64
+		r := &points.TransactionRule{}
+		if err := rows.Scan(&transactionID, &r.RuleID, &r.Points); err != nil {
+			return nil, err
+		}
+		rules[transactionID] = append(rules[transactionID], r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *storagePoints) PeriodTotals(dto *points.HistoryQueryDTO) ([]*points.PeriodTotal, error) {
+	period, ok := periodExpressions[dto.Period]
+	if !ok {
+		return nil, customError.InvalidListQueryError
+	}
+	whereClause, args := historyFilter(dto)
+	q := fmt.Sprintf(`SELECT %s AS period,
+	COALESCE(SUM(CASE WHEN pt.amount > 0 THEN pt.amount END), 0),
+	COALESCE(-SUM(CASE WHEN pt.amount < 0 THEN pt.amount END), 0)
+FROM point_transactions pt%s GROUP BY period ORDER BY period DESC`, period, whereClause)
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var totals []*points.PeriodTotal
+	for rows.Next() {
+		p := &points.PeriodTotal{}
+		if err := rows.Scan(&p.Period, &p.Earned, &p.Spent); err != nil {
+			return nil, err
+		}
+		p.Net = p.Earned - p.Spent
+		totals = append(totals, p)
+	}
+	return totals, rows.Err()
+}
+
+func historyFilter(dto *points.HistoryQueryDTO) (string, []interface{}) {
+	where := []string{"pt.user_id = ?"}
+	args := []interface{}{dto.UserID}
+	if dto.From != nil {
+		where = append(where, "pt.created_at >= ?")
+		args = append(args, dto.From.UTC())
+	}
+	if dto.To != nil {
+		where = append(where, "pt.created_at < ?")
+		args = append(args, dto.To.UTC())
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// expirableTotals sums per user what was earned before the cutoff minus every debit, the first parameter is the cutoff
+const expirableTotals = `SELECT user_id,
+	SUM(CASE WHEN amount > 0 AND created_at < ? THEN amount ELSE 0 END) + SUM(CASE WHEN amount < 0 THEN amount ELSE 0 END) AS expirable
+FROM point_transactions`
+
+func (s *storagePoints) ExpirablePoints(cutoff time.Time) ([]*points.Expiry, error) {
+	rows, err := s.db.Query(expirableTotals+` GROUP BY user_id HAVING expirable > 0 ORDER BY user_id`, cutoff.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var expiries []*points.Expiry
+	for rows.Next() {
+		e := &points.Expiry{}
+		if err := rows.Scan(&e.UserID, &e.Amount); err != nil {
+			return nil, err
+		}
+		expiries = append(expiries, e)
+	}
+	return expiries, rows.Err()
+}
+
+func (s *storagePoints) ExpirePoints(userID int64, cutoff time.Time, now time.Time) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Computed again inside the transaction, a redemption since ExpirablePoints leaves less to expire
+	var id, amount sql.NullInt64
+	if err := tx.QueryRow(expirableTotals+` WHERE user_id = ?`, cutoff.UTC(), userID).Scan(&id, &amount); err != nil {
+		return 0, err
+	}
+	if !amount.Valid || amount.Int64 <= 0 {
+		return 0, nil
+	}
+	t := &points.Transaction{
+		UserID:    userID,
+		Amount:    -amount.Int64,
+		Reason:    points.ReasonExpiry,
+		CreatedAt: now,
+	}
+	if err := InsertTransaction(tx, t); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return amount.Int64, nil
+}
+
+func (s *storagePoints) ExpiringNotices(cutoff time.Time, notifiedSince time.Time) ([]*points.ExpiryNotice, error) {
+	q := `SELECT e.user_id, u.email, e.expirable
+FROM (` + expirableTotals + ` GROUP BY user_id HAVING expirable > 0) e
+JOIN users u ON u.user_id = e.user_id
+LEFT JOIN point_expiry_notices n ON n.user_id = e.user_id
+WHERE u.blocked = 0 AND (n.notified_at IS NULL OR n.notified_at < ?)
+ORDER BY e.user_id`
+	rows, err := s.db.Query(q, cutoff.UTC(), notifiedSince.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notices []*points.ExpiryNotice
+	for rows.Next() {
+		n := &points.ExpiryNotice{}
+		if err := rows.Scan(&n.UserID, &n.Email, &n.Amount); err != nil {
+			return nil, err
+		}
+		notices = append(notices, n)
+	}
+	return notices, rows.Err()
+}
+
+func (s *storagePoints) RecordExpiryNotice(n *points.ExpiryNotice, now time.Time) error {
+	q := `INSERT INTO point_expiry_notices(user_id, amount, expires_before, notified_at) VALUES (?, ?, ?, ?)
+ON CONFLICT(user_id) DO UPDATE SET amount = excluded.amount, expires_before = excluded.expires_before, notified_at = excluded.notified_at`
+	_, err := s.db.Exec(q, n.UserID, n.Amount, n.ExpiresBefore.UTC(), now.UTC())
+	return err
+}
+
+func (s *storagePoints) ResetPoints(userID int64, now time.Time) (*points.Transaction, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var balance int64
+	if err := tx.QueryRow(`SELECT points FROM users WHERE user_id = ?`, userID).Scan(&balance); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	t := &points.Transaction{
+		UserID:    userID,
+		Amount:    -balance,
+		Reason:    points.ReasonAdjustment,
+		CreatedAt: now,
+	}
+	if balance == 0 {
+		return t, nil
+	}
+	if err := InsertTransaction(tx, t); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
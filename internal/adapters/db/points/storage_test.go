@@ -0,0 +1,151 @@
+package points
+
+import (
+	"auth-api/internal/domain/points"
+	"auth-api/pkg/client/mail"
+	"auth-api/pkg/client/sqlite"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a migrated database file with the options of config.json
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.NewDB("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := sqlite.Migrate(db, false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
+
+type entry struct {
+	amount int64
+	age    time.Duration
+}
+
+// createUser adds a user with the ledger entries, each one written age before now
+func createUser(t *testing.T, db *sql.DB, email string, now time.Time, entries []entry) int64 {
+	t.Helper()
+	result, err := db.Exec(`INSERT INTO users(email, password) VALUES (?, 'x')`, email)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	userID, _ := result.LastInsertId()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	for _, e := range entries {
+		reason := points.ReasonDeposit
+		if e.amount < 0 {
+			reason = points.ReasonRedemption
+		}
+		tr := &points.Transaction{UserID: userID, Amount: e.amount, Reason: reason, CreatedAt: now.Add(-e.age)}
+		if err := InsertTransaction(tx, tr); err != nil {
+			t.Fatalf("insert ledger entry: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	return userID
+}
+
+func balance(t *testing.T, db *sql.DB, userID int64) int64 {
+	t.Helper()
+	var points int64
+	if err := db.QueryRow(`SELECT points FROM users WHERE user_id = ?`, userID).Scan(&points); err != nil {
+		t.Fatalf("read balance: %v", err)
+	}
+	return points
+}
+
+const (
+	day   = 24 * time.Hour
+	month = 30 * day
+)
+
+func TestExpirePointsOldestSpentFirst(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		entries []entry
+		expired int64
+	}{
+		{"nothing old", []entry{{100, month}}, 0},
+		{"old points unspent", []entry{{100, 13 * month}, {40, month}}, 100},
+		{"debits spend the old points first", []entry{{100, 13 * month}, {50, 14 * month}, {40, month}, {-30, day}}, 120},
+		{"old points already spent", []entry{{50, 13 * month}, {100, month}, {-80, day}}, 0},
+		{"debit older than the cutoff", []entry{{100, 14 * month}, {-60, 13 * month}, {20, month}}, 40},
+	}
+	db := openTestDB(t)
+	s := NewPointsStorage(db)
+	cutoff := points.ExpiryCutoff(now)
+	for _, tt := range tests {
+		userID := createUser(t, db, tt.name+"@example.com", now, tt.entries)
+		before := balance(t, db, userID)
+		expired, err := s.ExpirePoints(userID, cutoff, now)
+		if err != nil {
+			t.Fatalf("%s: ExpirePoints: %v", tt.name, err)
+		}
+		if expired != tt.expired {
+			t.Errorf("%s: expired %d, want %d", tt.name, expired, tt.expired)
+		}
+		if got := balance(t, db, userID); got != before-tt.expired {
+			t.Errorf("%s: balance %d, want %d", tt.name, got, before-tt.expired)
+		}
+		// A second run at the same time has nothing left to expire
+		if expired, err := s.ExpirePoints(userID, cutoff, now); err != nil || expired != 0 {
+			t.Errorf("%s: second run expired %d, %v, want 0", tt.name, expired, err)
+		}
+	}
+}
+
+// noSessions is a SessionSettler without expired sessions
+type noSessions struct{}
+
+func (noSessions) SettleExpiredSessions(ctx context.Context, now time.Time) (int, error) {
+	return 0, nil
+}
+
+func TestRunMaintenanceRerun(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	db := openTestDB(t)
+	storage := NewPointsStorage(db)
+	service := points.NewPointsService(storage, mail.NewLogSender(), noSessions{})
+	old := createUser(t, db, "old@example.com", now, []entry{{100, 13 * month}, {40, month}, {-30, day}})
+	soon := createUser(t, db, "soon@example.com", now, []entry{{25, 12*month - 10*day}})
+
+	first, err := service.RunMaintenance(context.Background(), now, false)
+	if err != nil {
+		t.Fatalf("RunMaintenance: %v", err)
+	}
+	if first.ExpiredPoints != 70 || len(first.Expired) != 1 || first.Expired[0].UserID != old {
+		t.Errorf("first run expired %d point(s) of %d user(s), want 70 of user %d", first.ExpiredPoints, len(first.Expired), old)
+	}
+	if first.Notified != 1 || len(first.Mismatches) != 0 || len(first.Remaining) != 0 {
+		t.Errorf("first run notified %d, %d mismatch(es), %d left, want 1 notified and no mismatch", first.Notified, len(first.Mismatches), len(first.Remaining))
+	}
+
+	second, err := service.RunMaintenance(context.Background(), now.Add(time.Hour), false)
+	if err != nil {
+		t.Fatalf("second RunMaintenance: %v", err)
+	}
+	if second.ExpiredPoints != 0 || len(second.Expired) != 0 || second.Notified != 0 {
+		t.Errorf("second run expired %d point(s) and notified %d, want nothing", second.ExpiredPoints, second.Notified)
+	}
+	if got := balance(t, db, old); got != 40 {
+		t.Errorf("balance of the old points %d, want 40", got)
+	}
+	if got := balance(t, db, soon); got != 25 {
+		t.Errorf("balance of the points expiring soon %d, want 25", got)
+	}
+}
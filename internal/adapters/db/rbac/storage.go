@@ -0,0 +1,134 @@
+package rbac
+
+import (
+	"auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+)
+
+type storageRBAC struct {
+	db *sql.DB
+}
+
+func NewRBACStorage(db *sql.DB) rbac.RBACStorage {
+	return &storageRBAC{
+		db: db,
+	}
+}
+
+func (s *storageRBAC) GetUserRole(userID int64) (string, bool, error) {
+	var role string
+	var blocked bool
+	q := `SELECT role, blocked FROM users WHERE user_id = ?`
+	if err := s.db.QueryRow(q, userID).Scan(&role, &blocked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, customError.NotFoundError
+		}
+		return "", false, err
+	}
+	return role, blocked, nil
+}
+
+func (s *storageRBAC) SetUserRole(userID int64, role string) error {
+	q := `UPDATE users SET role = ? WHERE user_id = ?`
+	result, err := s.db.Exec(q, role, userID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (s *storageRBAC) GetRole(name string) (*rbac.Role, error) {
+	r := &rbac.Role{}
+	q := `SELECT name, description, require_2fa FROM roles WHERE name = ?`
+	if err := s.db.QueryRow(q, name).Scan(&r.Name, &r.Description, &r.RequireTwoFactor); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	permissions, err := s.rolePermissions(name)
+	if err != nil {
+		return nil, err
+	}
+	r.Permissions = permissions
+	return r, nil
+}
+
+func (s *storageRBAC) ListRoles() ([]*rbac.Role, error) {
+	rows, err := s.db.Query(`SELECT name, description, require_2fa FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	var roles []*rbac.Role
+	for rows.Next() {
+		r := &rbac.Role{}
+		if err := rows.Scan(&r.Name, &r.Description, &r.RequireTwoFactor); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, r := range roles {
+		if r.Permissions, err = s.rolePermissions(r.Name); err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+func (s *storageRBAC) SetRoleRequireTwoFactor(name string, required bool) error {
+	result, err := s.db.Exec(`UPDATE roles SET require_2fa = ? WHERE name = ?`, required, name)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (s *storageRBAC) UserHasTwoFactor(userID int64) (bool, error) {
+	var enabled bool
+	q := `SELECT totp_enabled FROM users WHERE user_id = ?`
+	if err := s.db.QueryRow(q, userID).Scan(&enabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, customError.NotFoundError
+		}
+		return false, err
+	}
+	return enabled, nil
+}
+
+func (s *storageRBAC) rolePermissions(role string) ([]string, error) {
+	rows, err := s.db.Query(`SELECT permission FROM role_permissions WHERE role = ? ORDER BY permission`, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	permissions := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, rows.Err()
+}
@@ -0,0 +1,235 @@
+package recycleBox
+
+import (
+	adaptersPoints "auth-api/internal/adapters/db/points"
+	adaptersRule "auth-api/internal/adapters/db/rule"
+	domainPoints "auth-api/internal/domain/points"
+	"auth-api/internal/domain/recycleBox"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const depositSessionColumns = `id, box_id, user_id, code_hash, status, bottles, points, created_at, expires_at, claimed_at, closed_at`
+
+func (s *storageRecycleBox) CreateDepositSession(ds *recycleBox.DepositSession) error {
+	q := `INSERT INTO deposit_sessions(box_id, code_hash, status, created_at, expires_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(q, ds.BoxID, ds.CodeHash, ds.Status, ds.CreatedAt, ds.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	ds.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *storageRecycleBox) GetDepositSession(id int64) (*recycleBox.DepositSession, error) {
+	return getDepositSession(s.db, id)
+}
+
+// ClaimDepositSession takes the session in one conditional update, of two concurrent claims only one wins
+func (s *storageRecycleBox) ClaimDepositSession(codeHash string, userId int64, now time.Time, expiresAt time.Time) (*recycleBox.DepositSession, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkVerifiedTx(tx, userId); err != nil {
+		return nil, err
+	}
+	var id int64
+	q := `UPDATE deposit_sessions SET status = ?, user_id = ?, claimed_at = ?, expires_at = ?
+		WHERE code_hash = ? AND status = ? AND expires_at > ?
+		RETURNING id`
+	err = tx.QueryRow(q, recycleBox.DepositSessionClaimed, userId, now, expiresAt,
+		codeHash, recycleBox.DepositSessionOpen, now).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.DepositSessionUnavailableError
+		}
+		return nil, err
+	}
+	ds, err := getDepositSession(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+func (s *storageRecycleBox) AddSessionBottle(sessionId int64, boxId int64, material string, now time.Time) (*recycleBox.DepositSession, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ds, err := getDepositSession(tx, sessionId)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return nil, customError.DepositSessionUnavailableError
+		}
+		return nil, err
+	}
+	d := &deposit{BoxID: boxId, UserID: ds.UserID, SessionID: &sessionId, Material: material, CreatedAt: now}
+	points, err := addBottleTx(tx, d)
+	if err != nil {
+		return nil, err
+	}
+	// The bottle is rolled back with the transaction when the session cannot take it
+	q := `UPDATE deposit_sessions SET bottles = bottles + 1, points = points + ?
+		WHERE id = ? AND box_id = ? AND status = ? AND expires_at > ?`
+	result, err := tx.Exec(q, points, sessionId, boxId, recycleBox.DepositSessionClaimed, now)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, customError.DepositSessionUnavailableError
+	}
+	if ds, err = getDepositSession(tx, sessionId); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// CloseDepositSession may close a claimed session after it expired, the bottles it counted are still paid
+func (s *storageRecycleBox) CloseDepositSession(sessionId int64, boxId int64, now time.Time, award recycleBox.AwardFunc) (*recycleBox.DepositSession, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ds, err := getDepositSession(tx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if ds.BoxID != boxId || ds.Status == recycleBox.DepositSessionClosed {
+		return nil, customError.DepositSessionUnavailableError
+	}
+	var awarded int64
+	if ds.UserID != nil && ds.Points > 0 {
+		awarded = ds.Points
+		t := &domainPoints.Transaction{
+			UserID: *ds.UserID,
+			BoxID:  &ds.BoxID,
+			Reason: domainPoints.ReasonDeposit,
+		}
+		box, err := boxBeforeSession(tx, ds)
+		if err != nil {
+			return nil, err
+		}
+		// Immediate transactions serialize the writers, the rules read the ledger through this one
+		extra, err := award(adaptersRule.NewUsageReader(tx), box, *ds.UserID, ds.Points)
+		if err != nil {
+			return nil, err
+		}
+		if extra != nil {
+			awarded += extra.Extra
+			t.Rules = extra.Rules
+		}
+		t.Amount = awarded
+		if err := adaptersPoints.InsertTransaction(tx, t); err != nil {
+			return nil, err
+		}
+	}
+	q := `UPDATE deposit_sessions SET status = ?, points = ?, closed_at = ? WHERE id = ? AND status != ?`
+	result, err := tx.Exec(q, recycleBox.DepositSessionClosed, awarded, now, sessionId, recycleBox.DepositSessionClosed)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, customError.DepositSessionUnavailableError
+	}
+	if ds, err = getDepositSession(tx, sessionId); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+func (s *storageRecycleBox) ExpiredDepositSessions(now time.Time) ([]*recycleBox.DepositSession, error) {
+	q := `SELECT ` + depositSessionColumns + ` FROM deposit_sessions WHERE status IN (?, ?) AND expires_at <= ? ORDER BY id`
+	rows, err := s.db.Query(q, recycleBox.DepositSessionOpen, recycleBox.DepositSessionClaimed, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sessions []*recycleBox.DepositSession
+	for rows.Next() {
+		ds := &recycleBox.DepositSession{}
+		if err := scanDepositSession(rows, ds); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, ds)
+	}
+	return sessions, rows.Err()
+}
+
+// boxBeforeSession reads the box of the session without the weight of the items the session counted,
+// the count does not go below zero when the box was emptied during the session
+func boxBeforeSession(db queryRower, ds *recycleBox.DepositSession) (*recycleBox.RecycleBox, error) {
+	rb := &recycleBox.RecycleBox{}
+	q := `SELECT ` + recycleBoxColumns + ` FROM recycle_boxes WHERE id = ?`
+	if err := scanRecycleBox(db.QueryRow(q, ds.BoxID), rb); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	var weight int64
+	q = `SELECT COALESCE(SUM(m.weight), 0) FROM deposits d JOIN materials m ON m.code = d.material WHERE d.session_id = ?`
+	if err := db.QueryRow(q, ds.ID).Scan(&weight); err != nil {
+		return nil, err
+	}
+	rb.Count = max(rb.Count-weight, 0)
+	return rb, nil
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func getDepositSession(db queryRower, id int64) (*recycleBox.DepositSession, error) {
+	ds := &recycleBox.DepositSession{}
+	q := `SELECT ` + depositSessionColumns + ` FROM deposit_sessions WHERE id = ?`
+	if err := scanDepositSession(db.QueryRow(q, id), ds); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return ds, nil
+}
+
+func scanDepositSession(row rowScanner, ds *recycleBox.DepositSession) error {
+	var userId sql.NullInt64
+	var claimedAt, closedAt sql.NullTime
+	err := row.Scan(&ds.ID, &ds.BoxID, &userId, &ds.CodeHash, &ds.Status, &ds.Bottles, &ds.Points,
+		&ds.CreatedAt, &ds.ExpiresAt, &claimedAt, &closedAt)
+	if err != nil {
+		return err
+	}
+	if userId.Valid {
+		ds.UserID = &userId.Int64
+	}
+	if claimedAt.Valid {
+		ds.ClaimedAt = &claimedAt.Time
+	}
+	if closedAt.Valid {
+		ds.ClosedAt = &closedAt.Time
+	}
+	return nil
+}
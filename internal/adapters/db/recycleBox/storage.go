@@ -1,16 +1,33 @@
 package recycleBox
 
 import (
+	"auth-api/internal/domain/material"
 	"auth-api/internal/domain/recycleBox"
 	customError "auth-api/internal/error"
+	"auth-api/pkg/client/sqlite"
 	"database/sql"
 	"errors"
-	"log"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"sort"
+	"strings"
+	"time"
 )
 
-const (
-	points = 100
-)
+// recycleBoxColumns is the column list scanned by scanRecycleBox, materials are collected from box_materials
+const recycleBoxColumns = `id, title, address, capacity, count, latitude, longitude,
+	(SELECT GROUP_CONCAT(material) FROM box_materials WHERE box_id = recycle_boxes.id)`
+
+// sortColumns maps the public sort fields to SQL expressions, it is the only list of the fields
+// GET /recyclebox can be sorted by
+var sortColumns = map[string]string{
+	"id":       "id",
+	"title":    "title",
+	"address":  "address",
+	"capacity": "capacity",
+	"count":    "count",
+	"fill":     "CAST(count AS REAL) / capacity",
+}
 
 func NewRecycleBoxStorage(db *sql.DB) recycleBox.RecycleBoxStorage {
 	return &storageRecycleBox{
@@ -24,9 +41,9 @@ type storageRecycleBox struct {
 
 func (s *storageRecycleBox) GetRecycleBox(id int64) (*recycleBox.RecycleBox, error) {
 	rb := &recycleBox.RecycleBox{}
-	q := `SELECT id, title, address, capacity, count FROM recycle_boxes WHERE id = ?`
+	q := `SELECT ` + recycleBoxColumns + ` FROM recycle_boxes WHERE id = ?`
 	row := s.db.QueryRow(q, id)
-	if err := row.Scan(&rb.Id, &rb.Title, &rb.Address, &rb.Capacity, &rb.Count); err != nil {
+	if err := scanRecycleBox(row, rb); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.NotFoundError
 		}
@@ -35,91 +52,338 @@ func (s *storageRecycleBox) GetRecycleBox(id int64) (*recycleBox.RecycleBox, err
 	return rb, nil
 }
 
-// CreateRecycleBox inserts a new RecycleBox using a DTO
-func (s *storageRecycleBox) CreateRecycleBox(dto *recycleBox.CreateRecycleBoxDTO) (*recycleBox.RecycleBox, error) {
-	q := `INSERT INTO recycle_boxes(title, address, capacity, count) VALUES (?, ?, ?, 0)`
-	result, err := s.db.Exec(q, dto.Title, dto.Address, dto.Capacity)
+// ListRecycleBoxes returns one page of boxes and the total number of matches
+func (s *storageRecycleBox) ListRecycleBoxes(dto *recycleBox.ListRecycleBoxDTO) ([]*recycleBox.RecycleBox, int64, error) {
+	column, ok := sortColumns[dto.Sort]
+	if !ok {
+		return nil, 0, customError.InvalidListQueryError
+	}
+	var where []string
+	var args []interface{}
+	if dto.Query != "" {
+		where = append(where, `(title LIKE ? ESCAPE '\' OR address LIKE ? ESCAPE '\')`)
+		pattern := "%" + sqlite.EscapeLike(dto.Query) + "%"
+		args = append(args, pattern, pattern)
+	}
+	if dto.Full != nil {
+		if *dto.Full {
+			where = append(where, "count >= capacity")
+		} else {
+			where = append(where, "count < capacity")
+		}
+	}
+	whereClause := ""
+	if len(where) > 0 {
+		whereClause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int64
+	qCount := `SELECT COUNT(*) FROM recycle_boxes` + whereClause
+	if err := s.db.QueryRow(qCount, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "ASC"
+	if dto.Order == "desc" {
+		order = "DESC"
+	}
+	q := fmt.Sprintf(`SELECT %s FROM recycle_boxes%s ORDER BY %s %s, id %s LIMIT ? OFFSET ?`, recycleBoxColumns, whereClause, column, order, order)
+	rows, err := s.db.Query(q, append(args, dto.Limit, dto.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var boxes []*recycleBox.RecycleBox
+	for rows.Next() {
+		rb := &recycleBox.RecycleBox{}
+		if err := scanRecycleBox(rows, rb); err != nil {
+			return nil, 0, err
+		}
+		boxes = append(boxes, rb)
+	}
+	return boxes, total, rows.Err()
+}
+
+// RecycleBoxesInBoundingBox returns the located boxes inside the coordinate range
+func (s *storageRecycleBox) RecycleBoxesInBoundingBox(dto *recycleBox.BoundingBoxDTO) ([]*recycleBox.RecycleBox, error) {
+	q := `SELECT ` + recycleBoxColumns + ` FROM recycle_boxes
+WHERE latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?`
+	if dto.ExcludeFull {
+		q += ` AND count < capacity`
+	}
+	rows, err := s.db.Query(q, dto.MinLatitude, dto.MaxLatitude, dto.MinLongitude, dto.MaxLongitude)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+	var boxes []*recycleBox.RecycleBox
+	for rows.Next() {
+		rb := &recycleBox.RecycleBox{}
+		if err := scanRecycleBox(rows, rb); err != nil {
+			return nil, err
+		}
+		boxes = append(boxes, rb)
+	}
+	return boxes, rows.Err()
+}
 
-	id, err := result.LastInsertId()
+// CreateRecycleBox inserts a new RecycleBox and the materials it accepts using a DTO
+func (s *storageRecycleBox) CreateRecycleBox(dto *recycleBox.CreateRecycleBoxDTO) (*recycleBox.RecycleBox, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &recycleBox.RecycleBox{
-		Id:       id,
-		Title:    dto.Title,
-		Address:  dto.Address,
-		Capacity: dto.Capacity,
-		Count:    0,
-	}, nil
-}
+	q := `INSERT INTO recycle_boxes(title, address, capacity, count, latitude, longitude) VALUES (?, ?, ?, 0, ?, ?)`
+	result, err := tx.Exec(q, dto.Title, dto.Address, dto.Capacity, dto.Latitude, dto.Longitude)
+	if err != nil {
+		return nil, err
+	}
 
-// UpdateRecycleBox updates an existing RecycleBox based on the provided DTO
-func (s *storageRecycleBox) UpdateRecycleBox(id int64, dto *recycleBox.UpdateRecycleBoxDTO) (*recycleBox.RecycleBox, error) {
-	q := `UPDATE recycle_boxes SET title = ?, address = ?, capacity = ?, count = ? WHERE id = ?`
-	_, err := s.db.Exec(q, dto.Title, dto.Address, dto.Capacity, dto.Count, id)
+	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
+	if err := insertBoxMaterialsTx(tx, id, dto.Materials); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	return s.GetRecycleBox(id)
 }
 
-func (s *storageRecycleBox) FlushRecycleBox(id int64) (*recycleBox.RecycleBox, error) {
-	q := `UPDATE recycle_boxes SET count = 0 WHERE id = ?`
-	_, err := s.db.Exec(q, id)
+// SetBoxMaterials replaces the materials the box accepts
+func (s *storageRecycleBox) SetBoxMaterials(id int64, materials []string) (*recycleBox.RecycleBox, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM recycle_boxes WHERE id = ?`, id).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM box_materials WHERE box_id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err := insertBoxMaterialsTx(tx, id, materials); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return s.GetRecycleBox(id)
 }
 
-func (s *storageRecycleBox) AddBottleWithPoints(boxId int64, userId int64) (*recycleBox.RecycleBox, error) {
-	// First, call AddBottle to increment the count in the recycle box
-	rb, err := s.AddBottle(boxId)
-	if err != nil {
-		return nil, err
+// insertBoxMaterialsTx links the materials to the box, an unknown material code fails the foreign key
+func insertBoxMaterialsTx(tx *sql.Tx, id int64, materials []string) error {
+	for _, m := range materials {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO box_materials(box_id, material) VALUES (?, ?)`, id, m); err != nil {
+			var sqliteErr sqlite3.Error
+			if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+				return customError.InvalidMaterialError
+			}
+			return err
+		}
 	}
-	log.Println("userid: ", userId)
-	// Award points to the user
-	q := `UPDATE users SET points = points + ? WHERE user_id = ?`
-	_, err = s.db.Exec(q, 100, userId)
+	return nil
+}
+
+// UpdateRecycleBox updates an existing RecycleBox based on the provided DTO
+func (s *storageRecycleBox) UpdateRecycleBox(id int64, dto *recycleBox.UpdateRecycleBoxDTO) (*recycleBox.RecycleBox, error) {
+	// Coordinates are optional in the update, missing ones keep the stored location
+	q := `UPDATE recycle_boxes SET title = ?, address = ?, capacity = ?, count = ?, latitude = COALESCE(?, latitude), longitude = COALESCE(?, longitude) WHERE id = ?`
+	_, err := s.db.Exec(q, dto.Title, dto.Address, dto.Capacity, dto.Count, dto.Latitude, dto.Longitude, id)
 	if err != nil {
-		log.Println(err.Error())
 		return nil, err
 	}
 
-	return rb, nil
+	return s.GetRecycleBox(id)
 }
 
-func (s *storageRecycleBox) AddBottle(id int64) (*recycleBox.RecycleBox, error) {
-	// First, retrieve the current count and capacity to check if the box is full
-	rb := &recycleBox.RecycleBox{}
-	qSelect := `SELECT id, title, address, capacity, count FROM recycle_boxes WHERE id = ?`
-	row := s.db.QueryRow(qSelect, id)
-	if err := row.Scan(&rb.Id, &rb.Title, &rb.Address, &rb.Capacity, &rb.Count); err != nil {
+// FlushRecycleBox empties the box and records the collection in one transaction
+func (s *storageRecycleBox) FlushRecycleBox(id int64, collectorId int64) (*recycleBox.Collection, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c := &recycleBox.Collection{BoxID: id, CollectorID: collectorId, CollectedAt: time.Now().UTC()}
+	qSelect := `SELECT count FROM recycle_boxes WHERE id = ?`
+	if err := tx.QueryRow(qSelect, id).Scan(&c.Bottles); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.NotFoundError
 		}
 		return nil, err
 	}
+	if c.Bottles == 0 {
+		return nil, customError.BoxEmptyError
+	}
 
-	// Check if the RecycleBox is already at full capacity
-	if rb.Count >= rb.Capacity {
-		return nil, errors.New("recycle box is full")
+	qUpdate := `UPDATE recycle_boxes SET count = 0 WHERE id = ?`
+	if _, err := tx.Exec(qUpdate, id); err != nil {
+		return nil, err
+	}
+	qInsert := `INSERT INTO collections(box_id, collector_id, bottles, collected_at) VALUES (?, ?, ?, ?)`
+	result, err := tx.Exec(qInsert, c.BoxID, c.CollectorID, c.Bottles, c.CollectedAt)
+	if err != nil {
+		return nil, err
+	}
+	if c.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *storageRecycleBox) ListCollections(dto *recycleBox.ListCollectionsDTO) ([]*recycleBox.Collection, error) {
+	q := `SELECT id, box_id, COALESCE(collector_id, 0), bottles, collected_at FROM collections
+WHERE box_id = ? ORDER BY collected_at DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(q, dto.BoxID, dto.Limit, dto.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var collections []*recycleBox.Collection
+	for rows.Next() {
+		c := &recycleBox.Collection{}
+		if err := rows.Scan(&c.ID, &c.BoxID, &c.CollectorID, &c.Bottles, &c.CollectedAt); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
 	}
+	return collections, rows.Err()
+}
 
-	// Increment the count
-	qUpdate := `UPDATE recycle_boxes SET count = count + 1 WHERE id = ?`
-	_, err := s.db.Exec(qUpdate, id)
+func (s *storageRecycleBox) AddBottle(id int64, code string) (*recycleBox.RecycleBox, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	if _, err := addBottleTx(tx, &deposit{BoxID: id, Material: code}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 
 	// Retrieve the updated record and return it
 	return s.GetRecycleBox(id)
 }
+
+// getBoxMaterial returns the material if it is active and the box accepts it
+func getBoxMaterial(db queryRower, boxId int64, code string) (*material.Material, error) {
+	m := &material.Material{}
+	q := `SELECT m.code, m.name, m.points, m.weight, m.active FROM box_materials bm JOIN materials m ON m.code = bm.material
+WHERE bm.box_id = ? AND bm.material = ? AND m.active = 1`
+	if err := db.QueryRow(q, boxId, code).Scan(&m.Code, &m.Name, &m.Points, &m.Weight, &m.Active); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err := boxExists(db, boxId); err != nil {
+			return nil, err
+		}
+		return nil, customError.MaterialNotAcceptedError
+	}
+	return m, nil
+}
+
+// deposit is a row of the deposit history, UserID is nil for anonymous deposits
+type deposit struct {
+	BoxID     int64
+	UserID    *int64
+	SessionID *int64
+	Material  string
+	CreatedAt time.Time
+}
+
+// addBottleTx counts an item of the material the box accepts, records it in the deposit history
+// and returns the points it is worth. The count grows by the weight of the material only while
+// the box has room, so concurrent deposits cannot overfill it.
+func addBottleTx(tx *sql.Tx, d *deposit) (int64, error) {
+	m, err := getBoxMaterial(tx, d.BoxID, d.Material)
+	if err != nil {
+		return 0, err
+	}
+
+	qUpdate := `UPDATE recycle_boxes SET count = count + ? WHERE id = ? AND count + ? <= capacity`
+	result, err := tx.Exec(qUpdate, m.Weight, d.BoxID, m.Weight)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		if err := boxExists(tx, d.BoxID); err != nil {
+			return 0, err
+		}
+		return 0, customError.BoxFullError
+	}
+
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	qDeposit := `INSERT INTO deposits(box_id, user_id, session_id, material, points, created_at) VALUES (?, ?, ?, ?, ?, ?)`
+	if _, err := tx.Exec(qDeposit, d.BoxID, d.UserID, d.SessionID, d.Material, m.Points, d.CreatedAt); err != nil {
+		return 0, err
+	}
+	return m.Points, nil
+}
+
+func boxExists(db queryRower, id int64) error {
+	var exists int
+	qSelect := `SELECT 1 FROM recycle_boxes WHERE id = ?`
+	if err := db.QueryRow(qSelect, id).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.NotFoundError
+		}
+		return err
+	}
+	return nil
+}
+
+// checkVerifiedTx refuses users who have not verified their email, only verified accounts earn points
+func checkVerifiedTx(tx *sql.Tx, userId int64) error {
+	var verified bool
+	if err := tx.QueryRow(`SELECT verified FROM users WHERE user_id = ?`, userId).Scan(&verified); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return customError.NotFoundError
+		}
+		return err
+	}
+	if !verified {
+		return customError.UserNotVerifiedError
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecycleBox(row rowScanner, rb *recycleBox.RecycleBox) error {
+	var materials sql.NullString
+	if err := row.Scan(&rb.Id, &rb.Title, &rb.Address, &rb.Capacity, &rb.Count, &rb.Latitude, &rb.Longitude, &materials); err != nil {
+		return err
+	}
+	rb.Materials = []string{}
+	if materials.String != "" {
+		rb.Materials = strings.Split(materials.String, ",")
+		sort.Strings(rb.Materials)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package referral
+
+import (
+	adaptersPoints "auth-api/internal/adapters/db/points"
+	domainPoints "auth-api/internal/domain/points"
+	"auth-api/internal/domain/referral"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+type storageReferral struct {
+	db *sql.DB
+}
+
+func NewReferralStorage(db *sql.DB) referral.ReferralStorage {
+	return &storageReferral{
+		db: db,
+	}
+}
+
+func (s *storageReferral) GetInviteCode(userID int64) (string, error) {
+	var code string
+	if err := s.db.QueryRow(`SELECT COALESCE(invite_code, '') FROM users WHERE user_id = ?`, userID).Scan(&code); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", customError.NotFoundError
+		}
+		return "", err
+	}
+	return code, nil
+}
+
+func (s *storageReferral) GetReferrerByCode(code string) (*referral.Referrer, error) {
+	r := &referral.Referrer{}
+	q := `SELECT user_id, email, blocked FROM users WHERE invite_code = ?`
+	if err := s.db.QueryRow(q, code).Scan(&r.ID, &r.Email, &r.Blocked); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *storageReferral) CreateReferral(r *referral.Referral) error {
+	q := `INSERT INTO referrals(referee_id, referrer_id, status, created_at) VALUES (?, ?, ?, ?)`
+	if _, err := s.db.Exec(q, r.RefereeID, r.ReferrerID, r.Status, r.CreatedAt); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+			return customError.InvalidReferralCodeError
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *storageReferral) CountReferrals(referrerID int64) (map[string]int64, error) {
+	rows, err := s.db.Query(`SELECT status, COUNT(*) FROM referrals WHERE referrer_id = ? GROUP BY status`, referrerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int64{}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *storageReferral) SettleReferral(refereeID int64, bonus *referral.Bonus, now time.Time) (*referral.Referral, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	r := &referral.Referral{RefereeID: refereeID}
+	var refereeBlocked, referrerBlocked bool
+	q := `SELECT r.referrer_id, r.created_at, referee.blocked, referrer.blocked FROM referrals r
+JOIN users referee ON referee.user_id = r.referee_id
+JOIN users referrer ON referrer.user_id = r.referrer_id
+WHERE r.referee_id = ? AND r.status = ?`
+	err = tx.QueryRow(q, refereeID, referral.StatusPending).Scan(&r.ReferrerID, &r.CreatedAt, &refereeBlocked, &referrerBlocked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// The transaction holds the write lock, so concurrent settlements cannot pass the cap
+	var rewarded int64
+	qRewarded := `SELECT COUNT(*) FROM referrals WHERE referrer_id = ? AND status = ?`
+	if err := tx.QueryRow(qRewarded, r.ReferrerID, referral.StatusRewarded).Scan(&rewarded); err != nil {
+		return nil, err
+	}
+	switch {
+	case refereeBlocked || referrerBlocked:
+		r.Status = referral.StatusBlocked
+	case rewarded >= bonus.MaxRewards:
+		r.Status = referral.StatusCapped
+	default:
+		r.Status = referral.StatusRewarded
+		credits := []*domainPoints.Transaction{
+			{UserID: r.ReferrerID, Amount: bonus.Referrer, Reason: domainPoints.ReasonReferral, CreatedAt: now},
+			{UserID: refereeID, Amount: bonus.Referee, Reason: domainPoints.ReasonReferral, CreatedAt: now},
+		}
+		for _, t := range credits {
+			if t.Amount <= 0 {
+				continue
+			}
+			if err := adaptersPoints.InsertTransaction(tx, t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r.SettledAt = &now
+	qSettle := `UPDATE referrals SET status = ?, settled_at = ? WHERE referee_id = ?`
+	if _, err := tx.Exec(qSettle, r.Status, now, refereeID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,240 @@
+package reward
+
+import (
+	adaptersPoints "auth-api/internal/adapters/db/points"
+	domainPoints "auth-api/internal/domain/points"
+	"auth-api/internal/domain/reward"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"github.com/mattn/go-sqlite3"
+	"time"
+)
+
+const (
+	rewardColumns     = `id, title, description, kind, partner_id, cost, stock, active, created_at`
+	redemptionColumns = `id, reward_id, user_id, transaction_id, code, cost, status, created_at, consumed_at, consumed_by`
+)
+
+type storageReward struct {
+	db *sql.DB
+}
+
+func NewRewardStorage(db *sql.DB) reward.RewardStorage {
+	return &storageReward{
+		db: db,
+	}
+}
+
+func (s *storageReward) ListRewards(activeOnly bool) ([]*reward.Reward, error) {
+	q := `SELECT ` + rewardColumns + ` FROM rewards`
+	if activeOnly {
+		q += ` WHERE active = 1`
+	}
+	rows, err := s.db.Query(q + ` ORDER BY cost, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rewards []*reward.Reward
+	for rows.Next() {
+		r := &reward.Reward{}
+		if err := scanReward(rows, r); err != nil {
+			return nil, err
+		}
+		rewards = append(rewards, r)
+	}
+	return rewards, rows.Err()
+}
+
+func (s *storageReward) GetReward(id int64) (*reward.Reward, error) {
+	r := &reward.Reward{}
+	if err := scanReward(s.db.QueryRow(`SELECT `+rewardColumns+` FROM rewards WHERE id = ?`, id), r); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *storageReward) CreateReward(r *reward.Reward) error {
+	q := `INSERT INTO rewards(title, description, kind, partner_id, cost, stock, active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(q, r.Title, r.Description, r.Kind, r.PartnerID, r.Cost, r.Stock, r.Active, r.CreatedAt)
+	if err != nil {
+		return constraintError(err)
+	}
+	r.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *storageReward) UpdateReward(r *reward.Reward) error {
+	q := `UPDATE rewards SET title = ?, description = ?, partner_id = ?, cost = ?, active = ? WHERE id = ?`
+	result, err := s.db.Exec(q, r.Title, r.Description, r.PartnerID, r.Cost, r.Active, r.ID)
+	if err != nil {
+		return constraintError(err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (s *storageReward) SetRewardStock(id int64, stock *int64) error {
+	_, err := s.db.Exec(`UPDATE rewards SET stock = ? WHERE id = ?`, stock, id)
+	return constraintError(err)
+}
+
+func (s *storageReward) Redeem(rewardID int64, userID int64, code string, now time.Time) (*reward.Redemption, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Reserve the stock first, the conditional update is what keeps it from going below zero
+	var cost int64
+	qStock := `UPDATE rewards SET stock = stock - 1 WHERE id = ? AND active = 1 AND (stock IS NULL OR stock > 0) RETURNING cost`
+	if err := tx.QueryRow(qStock, rewardID).Scan(&cost); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		var active bool
+		if err := tx.QueryRow(`SELECT active FROM rewards WHERE id = ?`, rewardID).Scan(&active); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, customError.NotFoundError
+			}
+			return nil, err
+		}
+		if !active {
+			return nil, customError.NotFoundError
+		}
+		return nil, customError.OutOfStockError
+	}
+
+	t := &domainPoints.Transaction{
+		UserID:    userID,
+		Amount:    -cost,
+		Reason:    domainPoints.ReasonRedemption,
+		CreatedAt: now,
+	}
+	if err := adaptersPoints.DebitTransaction(tx, t); err != nil {
+		return nil, err
+	}
+
+	rd := &reward.Redemption{
+		RewardID:      rewardID,
+		UserID:        userID,
+		TransactionID: &t.ID,
+		Code:          code,
+		Cost:          cost,
+		Status:        reward.RedemptionIssued,
+		CreatedAt:     now,
+	}
+	q := `INSERT INTO redemptions(reward_id, user_id, transaction_id, code, cost, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	result, err := tx.Exec(q, rd.RewardID, rd.UserID, rd.TransactionID, rd.Code, rd.Cost, rd.Status, rd.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if rd.ID, err = result.LastInsertId(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rd, nil
+}
+
+func (s *storageReward) ListRedemptions(userID int64) ([]*reward.Redemption, error) {
+	q := `SELECT ` + redemptionColumns + ` FROM redemptions WHERE user_id = ? ORDER BY created_at DESC, id DESC`
+	rows, err := s.db.Query(q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var redemptions []*reward.Redemption
+	for rows.Next() {
+		rd := &reward.Redemption{}
+		if err := scanRedemption(rows, rd); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, rd)
+	}
+	return redemptions, rows.Err()
+}
+
+func (s *storageReward) GetRedemptionByCode(code string) (*reward.Redemption, error) {
+	rd := &reward.Redemption{}
+	if err := scanRedemption(s.db.QueryRow(`SELECT `+redemptionColumns+` FROM redemptions WHERE code = ?`, code), rd); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return rd, nil
+}
+
+func (s *storageReward) ConsumeRedemption(id int64, partnerID int64, now time.Time) (*reward.Redemption, error) {
+	q := `UPDATE redemptions SET status = ?, consumed_at = ?, consumed_by = ? WHERE id = ? AND status = ?
+RETURNING ` + redemptionColumns
+	rd := &reward.Redemption{}
+	err := scanRedemption(s.db.QueryRow(q, reward.RedemptionConsumed, now, partnerID, id, reward.RedemptionIssued), rd)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.RedemptionConsumedError
+		}
+		return nil, err
+	}
+	return rd, nil
+}
+
+// constraintError maps an unknown partner or a negative stock to an invalid reward
+func constraintError(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint {
+		return customError.InvalidRewardError
+	}
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanReward(row rowScanner, r *reward.Reward) error {
+	var partnerID, stock sql.NullInt64
+	if err := row.Scan(&r.ID, &r.Title, &r.Description, &r.Kind, &partnerID, &r.Cost, &stock, &r.Active, &r.CreatedAt); err != nil {
+		return err
+	}
+	if partnerID.Valid {
+		r.PartnerID = &partnerID.Int64
+	}
+	if stock.Valid {
+		r.Stock = &stock.Int64
+	}
+	return nil
+}
+
+func scanRedemption(row rowScanner, rd *reward.Redemption) error {
+	var transactionID, consumedBy sql.NullInt64
+	var consumedAt sql.NullTime
+	err := row.Scan(&rd.ID, &rd.RewardID, &rd.UserID, &transactionID, &rd.Code, &rd.Cost, &rd.Status,
+		&rd.CreatedAt, &consumedAt, &consumedBy)
+	if err != nil {
+		return err
+	}
+	if transactionID.Valid {
+		rd.TransactionID = &transactionID.Int64
+	}
+	if consumedAt.Valid {
+		rd.ConsumedAt = &consumedAt.Time
+	}
+	if consumedBy.Valid {
+		rd.ConsumedBy = &consumedBy.Int64
+	}
+	return nil
+}
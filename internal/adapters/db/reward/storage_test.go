@@ -0,0 +1,132 @@
+package reward
+
+import (
+	"auth-api/internal/domain/reward"
+	customError "auth-api/internal/error"
+	"auth-api/pkg/client/sqlite"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// openTestDB opens a migrated database file with the options of config.json, the immediate
+// transactions and the busy timeout are what serialize the concurrent redemptions
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqlite.NewDB("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := sqlite.Migrate(db, false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	return db
+}
+
+func createUser(t *testing.T, db *sql.DB, email string, balance int64) int64 {
+	t.Helper()
+	result, err := db.Exec(`INSERT INTO users(email, password, points) VALUES (?, 'x', ?)`, email, balance)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+func createReward(t *testing.T, s reward.RewardStorage, cost int64, stock *int64) *reward.Reward {
+	t.Helper()
+	r := &reward.Reward{Title: "Coffee", Kind: reward.KindVoucher, Cost: cost, Stock: stock, Active: true, CreatedAt: time.Now().UTC()}
+	if err := s.CreateReward(r); err != nil {
+		t.Fatalf("CreateReward: %v", err)
+	}
+	return r
+}
+
+// redeemAll redeems the reward once for every user at the same time and counts the outcomes
+func redeemAll(t *testing.T, s reward.RewardStorage, rewardID int64, userIDs []int64) map[error]int {
+	t.Helper()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	outcomes := map[error]int{}
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func(i int, userID int64) {
+			defer wg.Done()
+			_, err := s.Redeem(rewardID, userID, fmt.Sprintf("CODE%08d", i), time.Now().UTC())
+			mu.Lock()
+			outcomes[err]++
+			mu.Unlock()
+		}(i, userID)
+	}
+	wg.Wait()
+	for err, n := range outcomes {
+		if err != nil && !errors.Is(err, customError.OutOfStockError) && !errors.Is(err, customError.InsufficientPointsError) {
+			t.Fatalf("Redeem failed %d times: %v", n, err)
+		}
+	}
+	return outcomes
+}
+
+func TestRedeemConcurrentStock(t *testing.T) {
+	db := openTestDB(t)
+	s := NewRewardStorage(db)
+	stock := int64(5)
+	r := createReward(t, s, 10, &stock)
+	var userIDs []int64
+	for i := 0; i < 20; i++ {
+		userIDs = append(userIDs, createUser(t, db, fmt.Sprintf("user%d@example.com", i), 100))
+	}
+
+	outcomes := redeemAll(t, s, r.ID, userIDs)
+	if outcomes[nil] != 5 || outcomes[customError.OutOfStockError] != 15 {
+		t.Errorf("%d redeemed and %d out of stock, want 5 and 15", outcomes[nil], outcomes[customError.OutOfStockError])
+	}
+	got, err := s.GetReward(r.ID)
+	if err != nil {
+		t.Fatalf("GetReward: %v", err)
+	}
+	if got.Stock == nil || *got.Stock != 0 {
+		t.Errorf("stock left %v, want 0", got.Stock)
+	}
+	var spent int64
+	if err := db.QueryRow(`SELECT COALESCE(SUM(100 - points), 0) FROM users`).Scan(&spent); err != nil {
+		t.Fatalf("sum balances: %v", err)
+	}
+	if spent != 5*r.Cost {
+		t.Errorf("users spent %d points, want %d", spent, 5*r.Cost)
+	}
+}
+
+func TestRedeemConcurrentBalance(t *testing.T) {
+	db := openTestDB(t)
+	s := NewRewardStorage(db)
+	r := createReward(t, s, 30, nil)
+	userID := createUser(t, db, "user@example.com", 100)
+	userIDs := make([]int64, 10)
+	for i := range userIDs {
+		userIDs[i] = userID
+	}
+
+	outcomes := redeemAll(t, s, r.ID, userIDs)
+	if outcomes[nil] != 3 || outcomes[customError.InsufficientPointsError] != 7 {
+		t.Errorf("%d redeemed and %d refused, want 3 and 7", outcomes[nil], outcomes[customError.InsufficientPointsError])
+	}
+	var balance, ledger, redemptions int64
+	if err := db.QueryRow(`SELECT points FROM users WHERE user_id = ?`, userID).Scan(&balance); err != nil {
+		t.Fatalf("read balance: %v", err)
+	}
+	if err := db.QueryRow(`SELECT COALESCE(SUM(amount), 0) FROM point_transactions WHERE user_id = ?`, userID).Scan(&ledger); err != nil {
+		t.Fatalf("sum ledger: %v", err)
+	}
+	if err := db.QueryRow(`SELECT COUNT(*) FROM redemptions WHERE user_id = ?`, userID).Scan(&redemptions); err != nil {
+		t.Fatalf("count redemptions: %v", err)
+	}
+	if balance != 10 || ledger != -90 || redemptions != 3 {
+		t.Errorf("balance %d, ledger %d, %d redemptions, want 10, -90 and 3", balance, ledger, redemptions)
+	}
+}
@@ -0,0 +1,190 @@
+package rule
+
+import (
+	"auth-api/internal/domain/points"
+	"auth-api/internal/domain/rule"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const ruleColumns = `id, name, description, multiplier, bonus, starts_at, ends_at, weekdays, box_ids,
+	zone_latitude, zone_longitude, zone_radius, first_deposit_of_day, max_fill_percent,
+	user_daily_cap, user_total_cap, priority, exclusive, active, created_at`
+
+type storageRule struct {
+	db *sql.DB
+}
+
+func NewRuleStorage(db *sql.DB) rule.RuleStorage {
+	return &storageRule{
+		db: db,
+	}
+}
+
+func (s *storageRule) ListRules() ([]*rule.Rule, error) {
+	return s.queryRules(`SELECT ` + ruleColumns + ` FROM point_rules ORDER BY id`)
+}
+
+func (s *storageRule) ListActiveRules(at time.Time) ([]*rule.Rule, error) {
+	q := `SELECT ` + ruleColumns + ` FROM point_rules
+WHERE active = 1 AND (starts_at IS NULL OR starts_at <= ?) AND (ends_at IS NULL OR ends_at > ?)
+ORDER BY priority DESC, id`
+	return s.queryRules(q, at.UTC(), at.UTC())
+}
+
+func (s *storageRule) GetRule(id int64) (*rule.Rule, error) {
+	r := &rule.Rule{}
+	if err := scanRule(s.db.QueryRow(`SELECT `+ruleColumns+` FROM point_rules WHERE id = ?`, id), r); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *storageRule) CreateRule(r *rule.Rule) error {
+	args, err := ruleArgs(r)
+	if err != nil {
+		return err
+	}
+	q := `INSERT INTO point_rules(name, description, multiplier, bonus, starts_at, ends_at, weekdays, box_ids,
+	zone_latitude, zone_longitude, zone_radius, first_deposit_of_day, max_fill_percent,
+	user_daily_cap, user_total_cap, priority, exclusive, active, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	result, err := s.db.Exec(q, append(args, r.CreatedAt)...)
+	if err != nil {
+		return err
+	}
+	r.ID, err = result.LastInsertId()
+	return err
+}
+
+func (s *storageRule) UpdateRule(r *rule.Rule) error {
+	args, err := ruleArgs(r)
+	if err != nil {
+		return err
+	}
+	q := `UPDATE point_rules SET name = ?, description = ?, multiplier = ?, bonus = ?, starts_at = ?, ends_at = ?,
+	weekdays = ?, box_ids = ?, zone_latitude = ?, zone_longitude = ?, zone_radius = ?, first_deposit_of_day = ?,
+	max_fill_percent = ?, user_daily_cap = ?, user_total_cap = ?, priority = ?, exclusive = ?, active = ?
+WHERE id = ?`
+	result, err := s.db.Exec(q, append(args, r.ID)...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (s *storageRule) queryRules(q string, args ...interface{}) ([]*rule.Rule, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var rules []*rule.Rule
+	for rows.Next() {
+		r := &rule.Rule{}
+		if err := scanRule(rows, r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// ruleArgs returns the column values of the rule in the order of CreateRule and UpdateRule
+func ruleArgs(r *rule.Rule) ([]interface{}, error) {
+	weekdays, err := json.Marshal(r.Weekdays)
+	if err != nil {
+		return nil, err
+	}
+	boxIDs, err := json.Marshal(r.BoxIDs)
+	if err != nil {
+		return nil, err
+	}
+	var zoneLat, zoneLng, zoneRadius *float64
+	if r.Zone != nil {
+		zoneLat, zoneLng, zoneRadius = &r.Zone.Latitude, &r.Zone.Longitude, &r.Zone.Radius
+	}
+	return []interface{}{r.Name, r.Description, r.Multiplier, r.Bonus, r.StartsAt, r.EndsAt, string(weekdays), string(boxIDs),
+		zoneLat, zoneLng, zoneRadius, r.FirstDepositOfDay, r.MaxFillPercent,
+		r.UserDailyCap, r.UserTotalCap, r.Priority, r.Exclusive, r.Active}, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRule(row rowScanner, r *rule.Rule) error {
+	var startsAt, endsAt sql.NullTime
+	var weekdays, boxIDs string
+	var zoneLat, zoneLng, zoneRadius sql.NullFloat64
+	var maxFill sql.NullInt64
+	err := row.Scan(&r.ID, &r.Name, &r.Description, &r.Multiplier, &r.Bonus, &startsAt, &endsAt, &weekdays, &boxIDs,
+		&zoneLat, &zoneLng, &zoneRadius, &r.FirstDepositOfDay, &maxFill,
+		&r.UserDailyCap, &r.UserTotalCap, &r.Priority, &r.Exclusive, &r.Active, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if startsAt.Valid {
+		r.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		r.EndsAt = &endsAt.Time
+	}
+	if err := json.Unmarshal([]byte(weekdays), &r.Weekdays); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(boxIDs), &r.BoxIDs); err != nil {
+		return err
+	}
+	if zoneLat.Valid && zoneLng.Valid && zoneRadius.Valid {
+		r.Zone = &rule.Zone{Latitude: zoneLat.Float64, Longitude: zoneLng.Float64, Radius: zoneRadius.Float64}
+	}
+	if maxFill.Valid {
+		r.MaxFillPercent = &maxFill.Int64
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type usageReader struct {
+	db queryRower
+}
+
+// NewUsageReader reads the rule usage through db, a *sql.Tx when it is read for a deposit being paid
+func NewUsageReader(db queryRower) rule.UsageReader {
+	return &usageReader{
+		db: db,
+	}
+}
+
+func (u *usageReader) CountDeposits(userID int64, since time.Time) (int64, error) {
+	var n int64
+	q := `SELECT COUNT(*) FROM point_transactions WHERE user_id = ? AND reason = ? AND created_at >= ?`
+	err := u.db.QueryRow(q, userID, points.ReasonDeposit, since.UTC()).Scan(&n)
+	return n, err
+}
+
+func (u *usageReader) RuleUsage(ruleID int64, userID int64, since time.Time) (int64, error) {
+	var used int64
+	q := `SELECT COALESCE(SUM(ptr.points), 0) FROM point_transaction_rules ptr
+JOIN point_transactions pt ON pt.id = ptr.transaction_id
+WHERE ptr.rule_id = ? AND pt.user_id = ? AND pt.created_at >= ?`
+	err := u.db.QueryRow(q, ruleID, userID, since.UTC()).Scan(&used)
+	return used, err
+}
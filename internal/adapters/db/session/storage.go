@@ -0,0 +1,78 @@
+package session
+
+import (
+	"auth-api/internal/domain/session"
+	customError "auth-api/internal/error"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type storageSession struct {
+	db *sql.DB
+}
+
+func NewSessionStorage(db *sql.DB) session.SessionStorage {
+	return &storageSession{
+		db: db,
+	}
+}
+
+func (ss *storageSession) CreateSession(s *session.Session) error {
+	q := `INSERT INTO sessions(family_id, user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?, ?)`
+	result, err := ss.db.Exec(q, s.FamilyID, s.UserID, s.TokenHash, s.ExpiresAt, s.CreatedAt)
+	if err != nil {
+		return err
+	}
+	s.ID, err = result.LastInsertId()
+	return err
+}
+
+func (ss *storageSession) GetSessionByTokenHash(hash string) (*session.Session, error) {
+	s := &session.Session{}
+	q := `SELECT id, family_id, user_id, token_hash, expires_at, used, revoked, created_at FROM sessions WHERE token_hash = ?`
+	row := ss.db.QueryRow(q, hash)
+	if err := row.Scan(&s.ID, &s.FamilyID, &s.UserID, &s.TokenHash, &s.ExpiresAt, &s.Used, &s.Revoked, &s.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// MarkSessionUsed flags the refresh token as rotated, it returns false if it was already used or revoked
+func (ss *storageSession) MarkSessionUsed(id int64) (bool, error) {
+	q := `UPDATE sessions SET used = 1 WHERE id = ? AND used = 0 AND revoked = 0`
+	result, err := ss.db.Exec(q, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (ss *storageSession) RevokeFamily(familyID string) error {
+	q := `UPDATE sessions SET revoked = 1 WHERE family_id = ?`
+	_, err := ss.db.Exec(q, familyID)
+	return err
+}
+
+func (ss *storageSession) RevokeUserSessions(userID int64) error {
+	q := `UPDATE sessions SET revoked = 1 WHERE user_id = ?`
+	_, err := ss.db.Exec(q, userID)
+	return err
+}
+
+// IsFamilyActive reports whether the family still has a live refresh token
+func (ss *storageSession) IsFamilyActive(familyID string) (bool, error) {
+	var n int64
+	q := `SELECT COUNT(*) FROM sessions WHERE family_id = ? AND revoked = 0 AND expires_at > ?`
+	if err := ss.db.QueryRow(q, familyID, time.Now().UTC()).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
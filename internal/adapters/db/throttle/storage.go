@@ -0,0 +1,64 @@
+package throttle
+
+import (
+	"auth-api/internal/domain/throttle"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type storageThrottle struct {
+	db *sql.DB
+}
+
+func NewAttemptStorage(db *sql.DB) throttle.AttemptStorage {
+	return &storageThrottle{
+		db: db,
+	}
+}
+
+func (s *storageThrottle) GetAttempts(key string) (*throttle.Attempts, error) {
+	a := &throttle.Attempts{Key: key}
+	var lockedUntil sql.NullTime
+	q := `SELECT failures, last_failure, locked_until FROM auth_attempts WHERE key = ?`
+	if err := s.db.QueryRow(q, key).Scan(&a.Failures, &a.LastFailure, &lockedUntil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return a, nil
+		}
+		return nil, err
+	}
+	a.LockedUntil = lockedUntil.Time
+	return a, nil
+}
+
+// AddFailure increments the counter in one statement, concurrent failures are all counted
+func (s *storageThrottle) AddFailure(key string, now time.Time, window time.Duration) (*throttle.Attempts, error) {
+	a := &throttle.Attempts{Key: key, LastFailure: now}
+	var lockedUntil sql.NullTime
+	q := `INSERT INTO auth_attempts(key, failures, last_failure) VALUES (?, 1, ?)
+		ON CONFLICT(key) DO UPDATE SET
+			failures = CASE WHEN last_failure < ? THEN 1 ELSE failures + 1 END,
+			last_failure = excluded.last_failure
+		RETURNING failures, locked_until`
+	if err := s.db.QueryRow(q, key, now, now.Add(-window)).Scan(&a.Failures, &lockedUntil); err != nil {
+		return nil, err
+	}
+	a.LockedUntil = lockedUntil.Time
+	return a, nil
+}
+
+func (s *storageThrottle) LockUntil(key string, until time.Time) error {
+	_, err := s.db.Exec(`UPDATE auth_attempts SET locked_until = ? WHERE key = ?`, until, key)
+	return err
+}
+
+func (s *storageThrottle) ResetAttempts(key string) error {
+	_, err := s.db.Exec(`DELETE FROM auth_attempts WHERE key = ?`, key)
+	return err
+}
+
+func (s *storageThrottle) DeleteStaleAttempts(before time.Time) error {
+	q := `DELETE FROM auth_attempts WHERE last_failure < ? AND (locked_until IS NULL OR locked_until < ?)`
+	_, err := s.db.Exec(q, before, before)
+	return err
+}
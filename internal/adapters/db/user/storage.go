@@ -3,11 +3,17 @@ package user
 import (
 	"auth-api/internal/domain/user"
 	customError "auth-api/internal/error"
+	"auth-api/pkg/client/sqlite"
 	"database/sql"
 	"errors"
 	"fmt"
+	"github.com/mattn/go-sqlite3"
 )
 
+// userColumns is the column list scanned by scanUser, never use SELECT * as columns are added by migrations
+// birth_date is cast to TEXT, otherwise the driver parses the DATE column into a timestamp
+const userColumns = `user_id, email, username, password, phone_number, COALESCE(CAST(birth_date AS TEXT), ''), points, role, blocked, verified, totp_enabled`
+
 type storageUser struct {
 	db *sql.DB
 }
@@ -20,9 +26,9 @@ func NewUserStorage(db *sql.DB) user.StorageUser {
 
 func (su *storageUser) GetUserByEmail(email string) (*user.User, error) {
 	u := &user.User{}
-	q := `SELECT * FROM users WHERE email = ?`
+	q := `SELECT ` + userColumns + ` FROM users WHERE email = ?`
 	row := su.db.QueryRow(q, email)
-	if err := row.Scan(&u.ID, &u.Email, &u.Username, &u.HashedPassword, &u.PhoneNumber, &u.BirthDate, &u.Role); err != nil {
+	if err := scanUser(row, u); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.NotFoundError
 		}
@@ -33,9 +39,9 @@ func (su *storageUser) GetUserByEmail(email string) (*user.User, error) {
 
 func (su *storageUser) GetUserById(id int64) (*user.User, error) {
 	u := &user.User{}
-	q := `SELECT * FROM users WHERE users.user_id = ?`
+	q := `SELECT ` + userColumns + ` FROM users WHERE users.user_id = ?`
 	row := su.db.QueryRow(q, id)
-	if err := row.Scan(&u.ID, &u.Email, &u.Username, &u.HashedPassword, &u.PhoneNumber, &u.BirthDate, &u.Points, &u.Role); err != nil {
+	if err := scanUser(row, u); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.NotFoundError
 		}
@@ -59,9 +65,9 @@ func (su *storageUser) GetUserPasswordById(id int64) (*user.AuthDTO, error) {
 
 func (su *storageUser) GetUserPasswordByEmail(email string) (*user.AuthDTO, error) {
 	u := &user.AuthDTO{}
-	q := `SELECT user_id, password, role FROM users WHERE users.email = ?`
+	q := `SELECT user_id, password, role, blocked, totp_enabled FROM users WHERE users.email = ?`
 	row := su.db.QueryRow(q, email)
-	if err := row.Scan(&u.ID, &u.HashedPassword, &u.Role); err != nil {
+	if err := row.Scan(&u.ID, &u.HashedPassword, &u.Role, &u.Blocked, &u.TwoFactor); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, customError.NotFoundError
 		}
@@ -70,13 +76,360 @@ func (su *storageUser) GetUserPasswordByEmail(email string) (*user.AuthDTO, erro
 	return u, nil
 }
 
+func (su *storageUser) ListUsers(dto *user.ListUsersDTO) ([]*user.User, int64, error) {
+	where := ""
+	var args []interface{}
+	if dto.Query != "" {
+		where = ` WHERE email LIKE ? ESCAPE '\' OR username LIKE ? ESCAPE '\'`
+		pattern := "%" + sqlite.EscapeLike(dto.Query) + "%"
+		args = append(args, pattern, pattern)
+	}
+
+	var total int64
+	if err := su.db.QueryRow(`SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	q := `SELECT ` + userColumns + ` FROM users` + where + ` ORDER BY user_id LIMIT ? OFFSET ?`
+	rows, err := su.db.Query(q, append(args, dto.Limit, dto.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var users []*user.User
+	for rows.Next() {
+		u := &user.User{}
+		if err := scanUser(rows, u); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+	return users, total, rows.Err()
+}
+
+func (su *storageUser) SetUserBlocked(id int64, blocked bool) error {
+	q := `UPDATE users SET blocked = ? WHERE user_id = ?`
+	result, err := su.db.Exec(q, blocked, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return customError.NotFoundError
+	}
+	return nil
+}
+
+func (su *storageUser) CreateEmailChange(ec *user.EmailChange) error {
+	q := `INSERT INTO email_changes(user_id, new_email, token_hash, expires_at) VALUES (?, ?, ?, ?)`
+	result, err := su.db.Exec(q, ec.UserID, ec.NewEmail, ec.TokenHash, ec.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	ec.ID, err = result.LastInsertId()
+	return err
+}
+
+func (su *storageUser) GetEmailChangeByTokenHash(hash string) (*user.EmailChange, error) {
+	ec := &user.EmailChange{}
+	q := `SELECT id, user_id, new_email, token_hash, expires_at, used FROM email_changes WHERE token_hash = ?`
+	row := su.db.QueryRow(q, hash)
+	if err := row.Scan(&ec.ID, &ec.UserID, &ec.NewEmail, &ec.TokenHash, &ec.ExpiresAt, &ec.Used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return ec, nil
+}
+
+// ApplyEmailChange marks the change used and updates the email in one transaction,
+// other pending changes of the user are dropped
+func (su *storageUser) ApplyEmailChange(ec *user.EmailChange) error {
+	tx, err := su.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE email_changes SET used = 1 WHERE id = ? AND used = 0`, ec.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.InvalidConfirmationTokenError
+	}
+	// The token was delivered to the new address, so it is verified as well
+	if _, err := tx.Exec(`UPDATE users SET email = ?, verified = 1 WHERE user_id = ?`, ec.NewEmail, ec.UserID); err != nil {
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+			return customError.BusyUpdateEmailError
+		}
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE email_changes SET used = 1 WHERE user_id = ? AND used = 0`, ec.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (su *storageUser) CreatePasswordReset(pr *user.PasswordReset) error {
+	q := `INSERT INTO password_resets(user_id, token_hash, expires_at) VALUES (?, ?, ?)`
+	result, err := su.db.Exec(q, pr.UserID, pr.TokenHash, pr.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	pr.ID, err = result.LastInsertId()
+	return err
+}
+
+func (su *storageUser) GetPasswordResetByTokenHash(hash string) (*user.PasswordReset, error) {
+	pr := &user.PasswordReset{}
+	q := `SELECT id, user_id, token_hash, expires_at, used FROM password_resets WHERE token_hash = ?`
+	row := su.db.QueryRow(q, hash)
+	if err := row.Scan(&pr.ID, &pr.UserID, &pr.TokenHash, &pr.ExpiresAt, &pr.Used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return pr, nil
+}
+
+// ApplyPasswordReset consumes the token and sets the password in one transaction,
+// every other outstanding reset token of the user is invalidated too
+func (su *storageUser) ApplyPasswordReset(pr *user.PasswordReset, hashedPassword string) error {
+	tx, err := su.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE password_resets SET used = 1 WHERE id = ? AND used = 0`, pr.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.InvalidConfirmationTokenError
+	}
+	if _, err := tx.Exec(`UPDATE users SET password = ? WHERE user_id = ?`, hashedPassword, pr.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE password_resets SET used = 1 WHERE user_id = ? AND used = 0`, pr.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (su *storageUser) CreateEmailVerification(ev *user.EmailVerification) error {
+	q := `INSERT INTO email_verifications(user_id, token_hash, expires_at) VALUES (?, ?, ?)`
+	result, err := su.db.Exec(q, ev.UserID, ev.TokenHash, ev.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	ev.ID, err = result.LastInsertId()
+	return err
+}
+
+func (su *storageUser) GetEmailVerificationByTokenHash(hash string) (*user.EmailVerification, error) {
+	ev := &user.EmailVerification{}
+	q := `SELECT id, user_id, token_hash, expires_at, used FROM email_verifications WHERE token_hash = ?`
+	row := su.db.QueryRow(q, hash)
+	if err := row.Scan(&ev.ID, &ev.UserID, &ev.TokenHash, &ev.ExpiresAt, &ev.Used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return ev, nil
+}
+
+// ApplyEmailVerification consumes every verification token of the user and marks the account verified
+func (su *storageUser) ApplyEmailVerification(ev *user.EmailVerification) error {
+	tx, err := su.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`UPDATE email_verifications SET used = 1 WHERE id = ? AND used = 0`, ev.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.InvalidConfirmationTokenError
+	}
+	if _, err := tx.Exec(`UPDATE email_verifications SET used = 1 WHERE user_id = ? AND used = 0`, ev.UserID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE users SET verified = 1 WHERE user_id = ?`, ev.UserID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (su *storageUser) GetTwoFactor(userID int64) (*user.TwoFactor, error) {
+	tf := &user.TwoFactor{UserID: userID}
+	q := `SELECT totp_secret, totp_enabled, totp_last_step FROM users WHERE user_id = ?`
+	if err := su.db.QueryRow(q, userID).Scan(&tf.Secret, &tf.Enabled, &tf.LastStep); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return tf, nil
+}
+
+// SetTwoFactorSecret stores the secret of a new enrollment, an enabled secret is never replaced
+func (su *storageUser) SetTwoFactorSecret(userID int64, secret string) error {
+	q := `UPDATE users SET totp_secret = ?, totp_last_step = 0 WHERE user_id = ? AND totp_enabled = 0`
+	result, err := su.db.Exec(q, secret, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.TwoFactorEnabledError
+	}
+	return nil
+}
+
+// EnableTwoFactor turns two-factor authentication on and replaces the recovery codes in one transaction
+func (su *storageUser) EnableTwoFactor(userID int64, step int64, recoveryCodeHashes []string) error {
+	tx, err := su.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `UPDATE users SET totp_enabled = 1, totp_last_step = ? WHERE user_id = ? AND totp_enabled = 0 AND totp_secret != ''`
+	result, err := tx.Exec(q, step, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.TwoFactorEnabledError
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	for _, h := range recoveryCodeHashes {
+		if _, err := tx.Exec(`INSERT INTO recovery_codes(user_id, code_hash) VALUES (?, ?)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (su *storageUser) DisableTwoFactor(userID int64) error {
+	tx, err := su.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := `UPDATE users SET totp_secret = '', totp_enabled = 0, totp_last_step = 0 WHERE user_id = ?`
+	if _, err := tx.Exec(q, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTwoFactorStep records the time step of an accepted code, a step at or before the last one is refused
+func (su *storageUser) UseTwoFactorStep(userID int64, step int64) error {
+	q := `UPDATE users SET totp_last_step = ? WHERE user_id = ? AND totp_last_step < ?`
+	result, err := su.db.Exec(q, step, userID, step)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.TwoFactorCodeError
+	}
+	return nil
+}
+
+func (su *storageUser) UseRecoveryCode(userID int64, codeHash string) error {
+	q := `UPDATE recovery_codes SET used = 1
+		WHERE id = (SELECT id FROM recovery_codes WHERE user_id = ? AND code_hash = ? AND used = 0 LIMIT 1)`
+	result, err := su.db.Exec(q, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.TwoFactorCodeError
+	}
+	return nil
+}
+
+func (su *storageUser) CreateLoginChallenge(c *user.LoginChallenge) error {
+	q := `INSERT INTO login_challenges(user_id, token_hash, expires_at) VALUES (?, ?, ?)`
+	result, err := su.db.Exec(q, c.UserID, c.TokenHash, c.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	c.ID, err = result.LastInsertId()
+	return err
+}
+
+func (su *storageUser) GetLoginChallengeByTokenHash(hash string) (*user.LoginChallenge, error) {
+	c := &user.LoginChallenge{}
+	q := `SELECT id, user_id, token_hash, expires_at, attempts, used FROM login_challenges WHERE token_hash = ?`
+	row := su.db.QueryRow(q, hash)
+	if err := row.Scan(&c.ID, &c.UserID, &c.TokenHash, &c.ExpiresAt, &c.Attempts, &c.Used); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.NotFoundError
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func (su *storageUser) FailLoginChallenge(id int64) error {
+	_, err := su.db.Exec(`UPDATE login_challenges SET attempts = attempts + 1 WHERE id = ?`, id)
+	return err
+}
+
+// UseLoginChallenge consumes the challenge, it can complete only one login
+func (su *storageUser) UseLoginChallenge(id int64) error {
+	result, err := su.db.Exec(`UPDATE login_challenges SET used = 1 WHERE id = ? AND used = 0`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return customError.InvalidConfirmationTokenError
+	}
+	return nil
+}
+
 func (su *storageUser) CreateUser(u *user.User) error {
 	q := `INSERT INTO users(email, password) values(?,?)`
-	_, err := su.db.Exec(q, u.Email, u.HashedPassword)
+	result, err := su.db.Exec(q, u.Email, u.HashedPassword)
 	if err != nil {
 		return err
 	}
-	return nil
+	u.ID, err = result.LastInsertId()
+	return err
 }
 
 func (su *storageUser) UpdateUser(u *user.User) error {
@@ -133,3 +486,11 @@ func generateUpdateQuery(toUpdateUser, existedUser *user.User) (string, []interf
 	query := fmt.Sprintf("UPDATE users SET %s WHERE user_id=?", updateQuery)
 	return query, updates
 }
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner, u *user.User) error {
+	return row.Scan(&u.ID, &u.Email, &u.Username, &u.HashedPassword, &u.PhoneNumber, &u.BirthDate, &u.Points, &u.Role, &u.Blocked, &u.Verified, &u.TwoFactor)
+}
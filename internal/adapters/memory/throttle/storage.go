@@ -0,0 +1,71 @@
+package throttle
+
+import (
+	"auth-api/internal/domain/throttle"
+	"sync"
+	"time"
+)
+
+// storageThrottle keeps the counters in memory, they are lost on restart and not shared between instances
+type storageThrottle struct {
+	mu       sync.Mutex
+	attempts map[string]throttle.Attempts
+}
+
+func NewAttemptStorage() throttle.AttemptStorage {
+	return &storageThrottle{
+		attempts: make(map[string]throttle.Attempts),
+	}
+}
+
+func (s *storageThrottle) GetAttempts(key string) (*throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attempts[key]
+	if !ok {
+		return &throttle.Attempts{Key: key}, nil
+	}
+	return &a, nil
+}
+
+func (s *storageThrottle) AddFailure(key string, now time.Time, window time.Duration) (*throttle.Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a, ok := s.attempts[key]
+	if !ok || a.LastFailure.Before(now.Add(-window)) {
+		a.Key = key
+		a.Failures = 0
+	}
+	a.Failures++
+	a.LastFailure = now
+	s.attempts[key] = a
+	return &a, nil
+}
+
+func (s *storageThrottle) LockUntil(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.attempts[key]; ok {
+		a.LockedUntil = until
+		s.attempts[key] = a
+	}
+	return nil
+}
+
+func (s *storageThrottle) ResetAttempts(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *storageThrottle) DeleteStaleAttempts(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, a := range s.attempts {
+		if a.LastFailure.Before(before) && a.LockedUntil.Before(before) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
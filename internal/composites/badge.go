@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiBadge "auth-api/internal/adapters/api/badge"
+	adaptersBadge "auth-api/internal/adapters/db/badge"
+	domainBadge "auth-api/internal/domain/badge"
+	"database/sql"
+)
+
+type BadgeComposite struct {
+	Storage domainBadge.BadgeStorage
+	Service domainBadge.ServiceBadge
+	Handler api.Handler
+}
+
+func NewBadgeComposite(db *sql.DB) (*BadgeComposite, error) {
+	badgeStorage := adaptersBadge.NewBadgeStorage(db)
+	badgeService := domainBadge.NewBadgeService(badgeStorage)
+	badgeHandler := apiBadge.NewHandler(badgeService)
+	return &BadgeComposite{
+		Storage: badgeStorage,
+		Service: badgeService,
+		Handler: badgeHandler,
+	}, nil
+}
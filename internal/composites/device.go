@@ -0,0 +1,21 @@
+package composites
+
+import (
+	adaptersDevice "auth-api/internal/adapters/db/device"
+	domainDevice "auth-api/internal/domain/device"
+	"database/sql"
+)
+
+type DeviceComposite struct {
+	Storage domainDevice.DeviceStorage
+	Service domainDevice.ServiceDevice
+}
+
+func NewDeviceComposite(db *sql.DB) (*DeviceComposite, error) {
+	deviceStorage := adaptersDevice.NewDeviceStorage(db)
+	deviceService := domainDevice.NewDeviceService(deviceStorage)
+	return &DeviceComposite{
+		Storage: deviceStorage,
+		Service: deviceService,
+	}, nil
+}
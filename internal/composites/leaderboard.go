@@ -0,0 +1,27 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiLeaderboard "auth-api/internal/adapters/api/leaderboard"
+	adaptersLeaderboard "auth-api/internal/adapters/db/leaderboard"
+	domainLeaderboard "auth-api/internal/domain/leaderboard"
+	domainRecycleBox "auth-api/internal/domain/recycleBox"
+	"database/sql"
+)
+
+type LeaderboardComposite struct {
+	Storage domainLeaderboard.LeaderboardStorage
+	Service domainLeaderboard.ServiceLeaderboard
+	Handler api.Handler
+}
+
+func NewLeaderboardComposite(db *sql.DB, boxes domainRecycleBox.ServiceRecycleBox) (*LeaderboardComposite, error) {
+	leaderboardStorage := adaptersLeaderboard.NewLeaderboardStorage(db)
+	leaderboardService := domainLeaderboard.NewLeaderboardService(leaderboardStorage, boxes)
+	leaderboardHandler := apiLeaderboard.NewHandler(leaderboardService)
+	return &LeaderboardComposite{
+		Storage: leaderboardStorage,
+		Service: leaderboardService,
+		Handler: leaderboardHandler,
+	}, nil
+}
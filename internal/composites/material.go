@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiMaterial "auth-api/internal/adapters/api/material"
+	adaptersMaterial "auth-api/internal/adapters/db/material"
+	domainMaterial "auth-api/internal/domain/material"
+	"database/sql"
+)
+
+type MaterialComposite struct {
+	Storage domainMaterial.MaterialStorage
+	Service domainMaterial.ServiceMaterial
+	Handler api.Handler
+}
+
+func NewMaterialComposite(db *sql.DB) (*MaterialComposite, error) {
+	materialStorage := adaptersMaterial.NewMaterialStorage(db)
+	materialService := domainMaterial.NewMaterialService(materialStorage)
+	materialHandler := apiMaterial.NewHandler(materialService)
+	return &MaterialComposite{
+		Storage: materialStorage,
+		Service: materialService,
+		Handler: materialHandler,
+	}, nil
+}
@@ -0,0 +1,27 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiPoints "auth-api/internal/adapters/api/points"
+	adaptersPoints "auth-api/internal/adapters/db/points"
+	domainPoints "auth-api/internal/domain/points"
+	"auth-api/pkg/client/mail"
+	"database/sql"
+)
+
+type PointsComposite struct {
+	Storage domainPoints.PointsStorage
+	Service domainPoints.ServicePoints
+	Handler api.Handler
+}
+
+func NewPointsComposite(db *sql.DB, mailer mail.Sender, sessions domainPoints.SessionSettler) (*PointsComposite, error) {
+	pointsStorage := adaptersPoints.NewPointsStorage(db)
+	pointsService := domainPoints.NewPointsService(pointsStorage, mailer, sessions)
+	pointsHandler := apiPoints.NewHandler(pointsService)
+	return &PointsComposite{
+		Storage: pointsStorage,
+		Service: pointsService,
+		Handler: pointsHandler,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiRBAC "auth-api/internal/adapters/api/rbac"
+	adaptersRBAC "auth-api/internal/adapters/db/rbac"
+	domainRBAC "auth-api/internal/domain/rbac"
+	"database/sql"
+)
+
+type RBACComposite struct {
+	Storage domainRBAC.RBACStorage
+	Service domainRBAC.ServiceRBAC
+	Handler api.Handler
+}
+
+func NewRBACComposite(db *sql.DB) (*RBACComposite, error) {
+	rbacStorage := adaptersRBAC.NewRBACStorage(db)
+	rbacService := domainRBAC.NewRBACService(rbacStorage)
+	rbacHandler := apiRBAC.NewHandler(rbacService)
+	return &RBACComposite{
+		Storage: rbacStorage,
+		Service: rbacService,
+		Handler: rbacHandler,
+	}, nil
+}
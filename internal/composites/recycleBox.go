@@ -4,7 +4,11 @@ import (
 	"auth-api/internal/adapters/api"
 	apiRecycleBox "auth-api/internal/adapters/api/recycleBox"
 	adaptersRecycleBox "auth-api/internal/adapters/db/recycleBox"
+	domainBadge "auth-api/internal/domain/badge"
+	domainDevice "auth-api/internal/domain/device"
 	domainRecycleBox "auth-api/internal/domain/recycleBox"
+	domainReferral "auth-api/internal/domain/referral"
+	domainRule "auth-api/internal/domain/rule"
 	"database/sql"
 )
 
@@ -14,9 +18,9 @@ type RecycleBoxComposite struct {
 	Handler api.Handler
 }
 
-func NewRecycleBoxComposite(db *sql.DB) (*RecycleBoxComposite, error) {
+func NewRecycleBoxComposite(db *sql.DB, devices domainDevice.ServiceDevice, rules domainRule.ServiceRule, badges domainBadge.ServiceBadge, referrals domainReferral.ServiceReferral) (*RecycleBoxComposite, error) {
 	recycleBoxStorageStorage := adaptersRecycleBox.NewRecycleBoxStorage(db)
-	recycleBoxService := domainRecycleBox.NewRecycleBoxService(recycleBoxStorageStorage)
+	recycleBoxService := domainRecycleBox.NewRecycleBoxService(recycleBoxStorageStorage, devices, rules, badges, referrals)
 	recycleBoxHandler := apiRecycleBox.NewHandler(recycleBoxService)
 	return &RecycleBoxComposite{
 		Storage: recycleBoxStorageStorage,
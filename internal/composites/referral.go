@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiReferral "auth-api/internal/adapters/api/referral"
+	adaptersReferral "auth-api/internal/adapters/db/referral"
+	domainReferral "auth-api/internal/domain/referral"
+	"database/sql"
+)
+
+type ReferralComposite struct {
+	Storage domainReferral.ReferralStorage
+	Service domainReferral.ServiceReferral
+	Handler api.Handler
+}
+
+func NewReferralComposite(db *sql.DB) (*ReferralComposite, error) {
+	referralStorage := adaptersReferral.NewReferralStorage(db)
+	referralService := domainReferral.NewReferralService(referralStorage)
+	referralHandler := apiReferral.NewHandler(referralService)
+	return &ReferralComposite{
+		Storage: referralStorage,
+		Service: referralService,
+		Handler: referralHandler,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiReward "auth-api/internal/adapters/api/reward"
+	adaptersReward "auth-api/internal/adapters/db/reward"
+	domainReward "auth-api/internal/domain/reward"
+	"database/sql"
+)
+
+type RewardComposite struct {
+	Storage domainReward.RewardStorage
+	Service domainReward.ServiceReward
+	Handler api.Handler
+}
+
+func NewRewardComposite(db *sql.DB) (*RewardComposite, error) {
+	rewardStorage := adaptersReward.NewRewardStorage(db)
+	rewardService := domainReward.NewRewardService(rewardStorage)
+	rewardHandler := apiReward.NewHandler(rewardService)
+	return &RewardComposite{
+		Storage: rewardStorage,
+		Service: rewardService,
+		Handler: rewardHandler,
+	}, nil
+}
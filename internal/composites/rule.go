@@ -0,0 +1,26 @@
+package composites
+
+import (
+	"auth-api/internal/adapters/api"
+	apiRule "auth-api/internal/adapters/api/rule"
+	adaptersRule "auth-api/internal/adapters/db/rule"
+	domainRule "auth-api/internal/domain/rule"
+	"database/sql"
+)
+
+type RuleComposite struct {
+	Storage domainRule.RuleStorage
+	Service domainRule.ServiceRule
+	Handler api.Handler
+}
+
+func NewRuleComposite(db *sql.DB) (*RuleComposite, error) {
+	ruleStorage := adaptersRule.NewRuleStorage(db)
+	ruleService := domainRule.NewRuleService(ruleStorage)
+	ruleHandler := apiRule.NewHandler(ruleService)
+	return &RuleComposite{
+		Storage: ruleStorage,
+		Service: ruleService,
+		Handler: ruleHandler,
+	}, nil
+}
@@ -0,0 +1,32 @@
+package composites
+
+import (
+	adaptersThrottle "auth-api/internal/adapters/db/throttle"
+	memoryThrottle "auth-api/internal/adapters/memory/throttle"
+	domainThrottle "auth-api/internal/domain/throttle"
+	"database/sql"
+	"fmt"
+)
+
+type ThrottleComposite struct {
+	Storage domainThrottle.AttemptStorage
+	Service domainThrottle.ServiceThrottle
+}
+
+// NewThrottleComposite keeps the counters in SQLite ("sqlite", the default) or in memory ("memory")
+func NewThrottleComposite(db *sql.DB, driver string) (*ThrottleComposite, error) {
+	var attemptStorage domainThrottle.AttemptStorage
+	switch driver {
+	case "", "sqlite":
+		attemptStorage = adaptersThrottle.NewAttemptStorage(db)
+	case "memory":
+		attemptStorage = memoryThrottle.NewAttemptStorage()
+	default:
+		return nil, fmt.Errorf("unknown throttle driver %q", driver)
+	}
+	throttleService := domainThrottle.NewThrottleService(attemptStorage, domainThrottle.DefaultPolicies)
+	return &ThrottleComposite{
+		Storage: attemptStorage,
+		Service: throttleService,
+	}, nil
+}
@@ -3,24 +3,35 @@ package composites
 import (
 	"auth-api/internal/adapters/api"
 	apiUser "auth-api/internal/adapters/api/user"
+	adaptersSession "auth-api/internal/adapters/db/session"
 	adaptersUser "auth-api/internal/adapters/db/user"
+	domainReferral "auth-api/internal/domain/referral"
+	domainSession "auth-api/internal/domain/session"
+	domainThrottle "auth-api/internal/domain/throttle"
 	domainUser "auth-api/internal/domain/user"
+	"auth-api/pkg/client/mail"
 	"database/sql"
 )
 
 type UserComposite struct {
-	Storage domainUser.StorageUser
-	Service domainUser.ServiceUser
-	Handler api.Handler
+	Storage        domainUser.StorageUser
+	Service        domainUser.ServiceUser
+	SessionStorage domainSession.SessionStorage
+	SessionService domainSession.ServiceSession
+	Handler        api.Handler
 }
 
-func NewUserComposite(db *sql.DB) (*UserComposite, error) {
+func NewUserComposite(db *sql.DB, mailer mail.Sender, throttle domainThrottle.ServiceThrottle, referrals domainReferral.ServiceReferral) (*UserComposite, error) {
+	sessionStorage := adaptersSession.NewSessionStorage(db)
+	sessionService := domainSession.NewSessionService(sessionStorage)
 	userStorage := adaptersUser.NewUserStorage(db)
-	userService := domainUser.NewUserService(userStorage)
-	userHandler := apiUser.NewHandler(userService)
+	userService := domainUser.NewUserService(userStorage, sessionService, mailer, referrals)
+	userHandler := apiUser.NewHandler(userService, throttle)
 	return &UserComposite{
-		Storage: userStorage,
-		Service: userService,
-		Handler: userHandler,
+		Storage:        userStorage,
+		Service:        userService,
+		SessionStorage: sessionStorage,
+		SessionService: sessionService,
+		Handler:        userHandler,
 	}, nil
 }
@@ -8,18 +8,37 @@ import (
 
 type Config struct {
 	Listener struct {
-		Protocol     string `json:"protocol"`
-		Host         string `json:"host"`
-		Port         string `json:"port"`
-		IdleTimeout  int    `json:"idle_timeout"`
-		WriteTimeout int    `json:"write_timeout"`
-		ReadTimeout  int    `json:"read_timeout"`
+		Protocol         string `json:"protocol"`
+		Host             string `json:"host"`
+		Port             string `json:"port"`
+		IdleTimeout      int    `json:"idle_timeout"`
+		WriteTimeout     int    `json:"write_timeout"`
+		ReadTimeout      int    `json:"read_timeout"`
+		TrustedProxyHops int    `json:"trusted_proxy_hops"` // proxies in front of the server, 0 ignores X-Forwarded-For
 	} `json:"listener"`
 	Database struct {
 		DbDriver string `json:"db_driver"`
 		DbName   string `json:"db_name"`
 		Config   string `json:"config"`
 	} `json:"storage"`
+	Auth struct {
+		TokenPrecedence []string `json:"token_precedence"`
+	} `json:"auth"`
+	Throttle struct {
+		Driver string `json:"driver"` // sqlite or memory
+	} `json:"throttle"`
+	Mail struct {
+		Driver   string `json:"driver"` // log, file or smtp
+		From     string `json:"from"`
+		FilePath string `json:"file_path"`
+		Host     string `json:"host"`
+		Port     string `json:"port"`
+		Username string `json:"username"`
+	} `json:"mail"`
+	Points struct {
+		MaintenanceInterval int  `json:"maintenance_interval"` // hours between runs of the points job, 0 disables it
+		ReconcileFix        bool `json:"reconcile_fix"`        // the job resets the mismatched balances, otherwise it only reports them
+	} `json:"points"`
 }
 
 func LoadConfiguration(file string) (cfg *Config, err error) {
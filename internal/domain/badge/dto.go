@@ -0,0 +1,26 @@
+package badge
+
+type CreateBadgeDTO struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Kind        string `json:"kind"`
+	Threshold   int64  `json:"threshold"`
+	BonusPoints int64  `json:"bonus_points"`
+}
+
+// UpdateBadgeDTO changes only the fields that are set, the badges already earned are kept
+type UpdateBadgeDTO struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Threshold   *int64  `json:"threshold"`
+	BonusPoints *int64  `json:"bonus_points"`
+	Active      *bool   `json:"active"`
+}
+
+// UserBadgesDTO is the response of GET /me/badges
+type UserBadgesDTO struct {
+	Stats      *Stats       `json:"stats"`
+	Earned     []*UserBadge `json:"earned"`
+	InProgress []*UserBadge `json:"in_progress"`
+}
@@ -0,0 +1,38 @@
+package badge
+
+import "time"
+
+// Kinds are the deposit counters a badge can be based on
+const (
+	KindBottles = "bottles" // items deposited
+	KindBoxes   = "boxes"   // distinct boxes used
+	KindStreak  = "streak"  // consecutive UTC days with a deposit, ending today or yesterday
+)
+
+// Badge is earned once the counter of its kind reaches Threshold, BonusPoints are credited when it is earned
+type Badge struct {
+	ID          int64     `json:"id"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Kind        string    `json:"kind"`
+	Threshold   int64     `json:"threshold"`
+	BonusPoints int64     `json:"bonus_points"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// UserBadge is a badge with the progress of a user towards it, Progress stops at the threshold
+type UserBadge struct {
+	*Badge
+	Progress  int64      `json:"progress"`
+	Earned    bool       `json:"earned"`
+	AwardedAt *time.Time `json:"awarded_at"`
+}
+
+// Stats are the deposit counters of a user
+type Stats struct {
+	Bottles int64 `json:"bottles"`
+	Boxes   int64 `json:"boxes"`
+	Streak  int64 `json:"streak"`
+}
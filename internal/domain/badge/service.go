@@ -0,0 +1,224 @@
+package badge
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var codePattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+type ServiceBadge interface {
+	ListBadges(ctx context.Context) ([]*Badge, error)
+	CreateBadge(ctx context.Context, dto *CreateBadgeDTO) (*Badge, error)
+	UpdateBadge(ctx context.Context, id int64, dto *UpdateBadgeDTO) (*Badge, error)
+	UserBadges(ctx context.Context, userID int64) (*UserBadgesDTO, error)
+	OnDeposit(ctx context.Context, userID int64) ([]*Badge, error)
+}
+
+type serviceBadge struct {
+	storage BadgeStorage
+}
+
+func NewBadgeService(storage BadgeStorage) ServiceBadge {
+	return &serviceBadge{
+		storage: storage,
+	}
+}
+
+// ListBadges returns every badge definition, inactive ones included
+func (s *serviceBadge) ListBadges(ctx context.Context) ([]*Badge, error) {
+	badges, err := s.storage.ListBadges(false)
+	if err != nil {
+		return nil, err
+	}
+	if badges == nil {
+		badges = []*Badge{}
+	}
+	return badges, nil
+}
+
+// CreateBadge adds a badge, users who already reached it get it with their next deposit
+func (s *serviceBadge) CreateBadge(ctx context.Context, dto *CreateBadgeDTO) (*Badge, error) {
+	b := &Badge{
+		Code:        strings.ToLower(strings.TrimSpace(dto.Code)),
+		Name:        strings.TrimSpace(dto.Name),
+		Description: strings.TrimSpace(dto.Description),
+		Kind:        dto.Kind,
+		Threshold:   dto.Threshold,
+		BonusPoints: dto.BonusPoints,
+		Active:      true,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := validBadge(b); err != nil {
+		return nil, err
+	}
+	if err := s.storage.CreateBadge(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *serviceBadge) UpdateBadge(ctx context.Context, id int64, dto *UpdateBadgeDTO) (*Badge, error) {
+	b, err := s.storage.GetBadge(id)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(dto.Name); name != "" {
+		b.Name = name
+	}
+	if dto.Description != nil {
+		b.Description = strings.TrimSpace(*dto.Description)
+	}
+	if dto.Threshold != nil {
+		b.Threshold = *dto.Threshold
+	}
+	if dto.BonusPoints != nil {
+		b.BonusPoints = *dto.BonusPoints
+	}
+	if dto.Active != nil {
+		b.Active = *dto.Active
+	}
+	if err := validBadge(b); err != nil {
+		return nil, err
+	}
+	if err := s.storage.UpdateBadge(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// UserBadges returns the badges the user earned, newest first, and the progress towards the active others
+func (s *serviceBadge) UserBadges(ctx context.Context, userID int64) (*UserBadgesDTO, error) {
+	badges, err := s.storage.ListBadges(false)
+	if err != nil {
+		return nil, err
+	}
+	earned, err := s.storage.EarnedBadges(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.stats(userID, badges, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	dto := &UserBadgesDTO{Stats: stats, Earned: []*UserBadge{}, InProgress: []*UserBadge{}}
+	for _, b := range badges {
+		ub := &UserBadge{Badge: b, Progress: min(stats.value(b.Kind), b.Threshold)}
+		if at, ok := earned[b.ID]; ok {
+			ub.Earned, ub.AwardedAt, ub.Progress = true, &at, b.Threshold
+			dto.Earned = append(dto.Earned, ub)
+		} else if b.Active {
+			dto.InProgress = append(dto.InProgress, ub)
+		}
+	}
+	sort.SliceStable(dto.Earned, func(i, j int) bool {
+		return dto.Earned[i].AwardedAt.After(*dto.Earned[j].AwardedAt)
+	})
+	return dto, nil
+}
+
+// OnDeposit awards the active badges the user has reached and returns the ones newly earned.
+// It is safe to call any number of times, a badge is awarded and its bonus credited only once.
+func (s *serviceBadge) OnDeposit(ctx context.Context, userID int64) ([]*Badge, error) {
+	badges, err := s.storage.ListBadges(true)
+	if err != nil {
+		return nil, err
+	}
+	earned, err := s.storage.EarnedBadges(userID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Badge
+	for _, b := range badges {
+		if _, ok := earned[b.ID]; !ok {
+			pending = append(pending, b)
+		}
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	stats, err := s.stats(userID, pending, now)
+	if err != nil {
+		return nil, err
+	}
+	var awarded []*Badge
+	for _, b := range pending {
+		if stats.value(b.Kind) < b.Threshold {
+			continue
+		}
+		ok, err := s.storage.AwardBadge(userID, b, now)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			awarded = append(awarded, b)
+		}
+	}
+	return awarded, nil
+}
+
+// stats computes the counters of the user, the streak is looked up only as far back as the badges need
+func (s *serviceBadge) stats(userID int64, badges []*Badge, now time.Time) (*Stats, error) {
+	stats := &Stats{}
+	var err error
+	if stats.Bottles, stats.Boxes, err = s.storage.DepositStats(userID); err != nil {
+		return nil, err
+	}
+	var maxStreak int64
+	for _, b := range badges {
+		if b.Kind == KindStreak {
+			maxStreak = max(maxStreak, b.Threshold)
+		}
+	}
+	if maxStreak > 0 {
+		days, err := s.storage.DepositDays(userID, maxStreak)
+		if err != nil {
+			return nil, err
+		}
+		stats.Streak = streak(days, now)
+	}
+	return stats, nil
+}
+
+// streak counts the consecutive days ending today or yesterday, days are newest first
+func streak(days []time.Time, now time.Time) int64 {
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if len(days) == 0 || days[0].Before(day.AddDate(0, 0, -1)) {
+		return 0
+	}
+	var n int64 = 1
+	for i := 1; i < len(days); i++ {
+		if !days[i].Equal(days[i-1].AddDate(0, 0, -1)) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+func (st *Stats) value(kind string) int64 {
+	switch kind {
+	case KindBottles:
+		return st.Bottles
+	case KindBoxes:
+		return st.Boxes
+	case KindStreak:
+		return st.Streak
+	}
+	return 0
+}
+
+func validBadge(b *Badge) error {
+	if !codePattern.MatchString(b.Code) || b.Name == "" || b.Threshold <= 0 || b.BonusPoints < 0 {
+		return customError.InvalidBadgeError
+	}
+	if b.Kind != KindBottles && b.Kind != KindBoxes && b.Kind != KindStreak {
+		return customError.InvalidBadgeError
+	}
+	return nil
+}
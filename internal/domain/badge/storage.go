@@ -0,0 +1,18 @@
+package badge
+
+import "time"
+
+type BadgeStorage interface {
+	ListBadges(activeOnly bool) ([]*Badge, error)
+	GetBadge(id int64) (*Badge, error)
+	CreateBadge(b *Badge) error
+	UpdateBadge(b *Badge) error
+	// EarnedBadges returns when the user earned each of their badges by badge ID
+	EarnedBadges(userID int64) (map[int64]time.Time, error)
+	// DepositStats counts the items the user deposited and the distinct boxes they used
+	DepositStats(userID int64) (bottles int64, boxes int64, err error)
+	// DepositDays returns up to limit UTC days on which the user deposited, newest first
+	DepositDays(userID int64, limit int64) ([]time.Time, error)
+	// AwardBadge records the badge and credits its bonus in one transaction, false when the user already has it
+	AwardBadge(userID int64, b *Badge, now time.Time) (bool, error)
+}
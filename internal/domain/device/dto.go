@@ -0,0 +1,8 @@
+package device
+
+// DeviceCredentialsDTO is returned once when the credentials are issued, the secret cannot be read later
+type DeviceCredentialsDTO struct {
+	BoxID     int64  `json:"box_id"`
+	Secret    string `json:"secret"`
+	Algorithm string `json:"algorithm"`
+}
@@ -0,0 +1,10 @@
+package device
+
+import "time"
+
+// Device holds the HMAC secret of the hardware installed in a recycle box
+type Device struct {
+	BoxID     int64
+	Secret    string
+	CreatedAt time.Time
+}
@@ -0,0 +1,106 @@
+package device
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureAlgorithm = "HMAC-SHA256"
+	// MaxClockSkew is how far the timestamp of a request may be from the server time
+	MaxClockSkew = time.Minute * 5
+	// nonces are kept while a request carrying them could still pass the timestamp check
+	nonceTTL      = MaxClockSkew * 2
+	purgeInterval = time.Minute * 10
+	maxNonceLen   = 64
+)
+
+type ServiceDevice interface {
+	IssueCredentials(ctx context.Context, boxID int64) (*DeviceCredentialsDTO, error)
+	VerifyDeviceRequest(ctx context.Context, boxID int64, method, uri, timestamp, nonce, signature string, body []byte) error
+}
+
+type serviceDevice struct {
+	storage DeviceStorage
+	purges  *utils.Interval
+}
+
+func NewDeviceService(storage DeviceStorage) ServiceDevice {
+	return &serviceDevice{
+		storage: storage,
+		purges:  &utils.Interval{Every: purgeInterval},
+	}
+}
+
+// IssueCredentials generates a new secret for the box, the previous one stops working
+func (s *serviceDevice) IssueCredentials(ctx context.Context, boxID int64) (*DeviceCredentialsDTO, error) {
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	d := &Device{BoxID: boxID, Secret: secret, CreatedAt: time.Now().UTC()}
+	if err := s.storage.SaveDevice(d); err != nil {
+		return nil, err
+	}
+	return &DeviceCredentialsDTO{BoxID: boxID, Secret: secret, Algorithm: SignatureAlgorithm}, nil
+}
+
+// VerifyDeviceRequest checks the signature and the timestamp of a device request and burns its nonce,
+// so a captured request cannot be replayed. uri includes the query string.
+func (s *serviceDevice) VerifyDeviceRequest(ctx context.Context, boxID int64, method, uri, timestamp, nonce, signature string, body []byte) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || nonce == "" || len(nonce) > maxNonceLen {
+		return customError.InvalidDeviceSignatureError
+	}
+	now := time.Now().UTC()
+	if skew := now.Sub(time.Unix(ts, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return customError.InvalidDeviceSignatureError
+	}
+	d, err := s.storage.GetDevice(boxID)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.InvalidDeviceSignatureError
+		}
+		return err
+	}
+	expected := Sign(d.Secret, method, uri, timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return customError.InvalidDeviceSignatureError
+	}
+	s.purge(now)
+	return s.storage.UseNonce(boxID, nonce, now.Add(nonceTTL))
+}
+
+// purge drops expired nonces at most once per purgeInterval, failures are only logged
+func (s *serviceDevice) purge(now time.Time) {
+	if !s.purges.Due(now) {
+		return
+	}
+	if err := s.storage.DeleteExpiredNonces(now); err != nil {
+		log.Printf("cannot purge device nonces: %v", err)
+	}
+}
+
+// Sign returns the hex encoded signature of a device request:
+// HMAC-SHA256(secret, method + "\n" + uri + "\n" + timestamp + "\n" + nonce + "\n" + hex(SHA-256(body)))
+func Sign(secret, method, uri, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(method),
+		uri,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,209 @@
+package device
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryStorage keeps devices and used nonces in maps, like the SQLite storage it refuses a nonce used twice by a box
+type memoryStorage struct {
+	devices map[int64]*Device
+	nonces  map[string]time.Time
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		devices: make(map[int64]*Device),
+		nonces:  make(map[string]time.Time),
+	}
+}
+
+func (s *memoryStorage) SaveDevice(d *Device) error {
+	s.devices[d.BoxID] = d
+	return nil
+}
+
+func (s *memoryStorage) GetDevice(boxID int64) (*Device, error) {
+	d, ok := s.devices[boxID]
+	if !ok {
+		return nil, customError.NotFoundError
+	}
+	return d, nil
+}
+
+func (s *memoryStorage) UseNonce(boxID int64, nonce string, expiresAt time.Time) error {
+	key := strconv.FormatInt(boxID, 10) + "/" + nonce
+	if _, ok := s.nonces[key]; ok {
+		return customError.DeviceReplayError
+	}
+	s.nonces[key] = expiresAt
+	return nil
+}
+
+func (s *memoryStorage) DeleteExpiredNonces(before time.Time) error {
+	for key, expiresAt := range s.nonces {
+		if expiresAt.Before(before) {
+			delete(s.nonces, key)
+		}
+	}
+	return nil
+}
+
+// The expected value is computed independently, with the HMAC of Python's standard library
+func TestSign(t *testing.T) {
+	got := Sign("secret", "post", "/device/deposits", "1700000000", "n-1", []byte(`{"material":"can"}`))
+	want := "5a09987deff052e187d41a801135c9c8e725de8b72b1803c1a5594f328e9d8aa"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestSignCoversEveryPart(t *testing.T) {
+	base := Sign("secret", "POST", "/device/deposits", "1700000000", "n-1", []byte("{}"))
+	tests := []struct {
+		name string
+		sig  string
+	}{
+		{"secret", Sign("other", "POST", "/device/deposits", "1700000000", "n-1", []byte("{}"))},
+		{"method", Sign("secret", "PUT", "/device/deposits", "1700000000", "n-1", []byte("{}"))},
+		{"uri", Sign("secret", "POST", "/device/deposits?x=1", "1700000000", "n-1", []byte("{}"))},
+		{"timestamp", Sign("secret", "POST", "/device/deposits", "1700000001", "n-1", []byte("{}"))},
+		{"nonce", Sign("secret", "POST", "/device/deposits", "1700000000", "n-2", []byte("{}"))},
+		{"body", Sign("secret", "POST", "/device/deposits", "1700000000", "n-1", []byte("{ }"))},
+	}
+	for _, tt := range tests {
+		if tt.sig == base {
+			t.Errorf("changing the %s does not change the signature", tt.name)
+		}
+	}
+}
+
+func TestVerifyDeviceRequest(t *testing.T) {
+	const (
+		boxID  = 7
+		secret = "box-secret"
+		method = "POST"
+		uri    = "/device/deposits"
+	)
+	body := []byte(`{"material":"bottle"}`)
+	now := time.Now()
+	ts := func(d time.Duration) string { return strconv.FormatInt(now.Add(d).Unix(), 10) }
+
+	tests := []struct {
+		name      string
+		boxID     int64
+		timestamp string
+		nonce     string
+		signature func(timestamp, nonce string) string
+		body      []byte
+		err       error
+	}{
+		{
+			name: "valid", boxID: boxID, timestamp: ts(0), nonce: "valid", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+		},
+		{
+			name: "upper case signature", boxID: boxID, timestamp: ts(0), nonce: "upper", body: body,
+			signature: func(timestamp, nonce string) string {
+				return strings.ToUpper(Sign(secret, method, uri, timestamp, nonce, body))
+			},
+		},
+		{
+			name: "wrong secret", boxID: boxID, timestamp: ts(0), nonce: "wrong-secret", body: body,
+			signature: func(timestamp, nonce string) string { return Sign("other", method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "tampered body", boxID: boxID, timestamp: ts(0), nonce: "tampered", body: []byte(`{"material":"can"}`),
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "unknown box", boxID: boxID + 1, timestamp: ts(0), nonce: "unknown", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "clock behind within skew", boxID: boxID, timestamp: ts(-4 * time.Minute), nonce: "behind", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+		},
+		{
+			name: "clock ahead within skew", boxID: boxID, timestamp: ts(4 * time.Minute), nonce: "ahead", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+		},
+		{
+			name: "clock too far behind", boxID: boxID, timestamp: ts(-MaxClockSkew - time.Minute), nonce: "old", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "clock too far ahead", boxID: boxID, timestamp: ts(MaxClockSkew + time.Minute), nonce: "future", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "timestamp not a number", boxID: boxID, timestamp: "soon", nonce: "nan", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "empty nonce", boxID: boxID, timestamp: ts(0), nonce: "", body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+		{
+			name: "nonce too long", boxID: boxID, timestamp: ts(0), nonce: strings.Repeat("n", maxNonceLen+1), body: body,
+			signature: func(timestamp, nonce string) string { return Sign(secret, method, uri, timestamp, nonce, body) },
+			err:       customError.InvalidDeviceSignatureError,
+		},
+	}
+
+	storage := newMemoryStorage()
+	storage.SaveDevice(&Device{BoxID: boxID, Secret: secret})
+	s := NewDeviceService(storage)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.VerifyDeviceRequest(context.Background(), tt.boxID, method, uri, tt.timestamp, tt.nonce, tt.signature(tt.timestamp, tt.nonce), tt.body)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("VerifyDeviceRequest = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyDeviceRequestNonceReplay(t *testing.T) {
+	storage := newMemoryStorage()
+	storage.SaveDevice(&Device{BoxID: 1, Secret: "s1"})
+	storage.SaveDevice(&Device{BoxID: 2, Secret: "s2"})
+	s := NewDeviceService(storage)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	verify := func(boxID int64, secret, nonce string) error {
+		sig := Sign(secret, "POST", "/device/sessions", timestamp, nonce, nil)
+		return s.VerifyDeviceRequest(context.Background(), boxID, "POST", "/device/sessions", timestamp, nonce, sig, nil)
+	}
+
+	tests := []struct {
+		name   string
+		boxID  int64
+		secret string
+		nonce  string
+		err    error
+	}{
+		{"first use", 1, "s1", "abc", nil},
+		{"replayed request", 1, "s1", "abc", customError.DeviceReplayError},
+		{"same nonce on another box", 2, "s2", "abc", nil},
+		{"new nonce", 1, "s1", "abd", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.boxID, tt.secret, tt.nonce); !errors.Is(err, tt.err) {
+				t.Errorf("VerifyDeviceRequest = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package device
+
+import "time"
+
+type DeviceStorage interface {
+	// SaveDevice creates the device of the box or replaces its secret
+	SaveDevice(d *Device) error
+	GetDevice(boxID int64) (*Device, error)
+	// UseNonce records the nonce, DeviceReplayError is returned when the box already used it
+	UseNonce(boxID int64, nonce string, expiresAt time.Time) error
+	DeleteExpiredNonces(before time.Time) error
+}
@@ -0,0 +1,31 @@
+package leaderboard
+
+import "time"
+
+// QueryDTO holds the parameters of GET /leaderboard, Radius is in meters.
+// BoxIDs is the scope resolved by the service, nil ranks deposits in every box.
+type QueryDTO struct {
+	UserID    int64
+	Metric    string
+	Period    string
+	BoxID     *int64
+	Latitude  *float64
+	Longitude *float64
+	Radius    float64
+	Limit     int64
+
+	Since  *time.Time
+	BoxIDs []int64
+}
+
+// BoardDTO is the response of GET /leaderboard, Me is nil when the caller has nothing to rank
+type BoardDTO struct {
+	Metric  string   `json:"metric"`
+	Period  string   `json:"period"`
+	Entries []*Entry `json:"entries"`
+	Me      *Entry   `json:"me"`
+}
+
+type SettingsDTO struct {
+	Public *bool `json:"public"`
+}
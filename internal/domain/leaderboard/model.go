@@ -0,0 +1,24 @@
+package leaderboard
+
+const (
+	MetricBottles = "bottles"
+	MetricPoints  = "points"
+
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+	PeriodAll   = "all"
+)
+
+// Entry is a ranked user, Name is the handle of users who opted out of the public leaderboards
+type Entry struct {
+	Rank  int64  `json:"rank"`
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+	You   bool   `json:"you,omitempty"`
+}
+
+// Settings is the leaderboard visibility of a user
+type Settings struct {
+	Public bool   `json:"public"`
+	Handle string `json:"handle"`
+}
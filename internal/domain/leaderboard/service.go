@@ -0,0 +1,136 @@
+package leaderboard
+
+import (
+	"auth-api/internal/domain/recycleBox"
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"time"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+type ServiceLeaderboard interface {
+	Board(ctx context.Context, dto *QueryDTO) (*BoardDTO, error)
+	GetSettings(ctx context.Context, userID int64) (*Settings, error)
+	UpdateSettings(ctx context.Context, userID int64, dto *SettingsDTO) (*Settings, error)
+}
+
+type serviceLeaderboard struct {
+	storage LeaderboardStorage
+	boxes   recycleBox.ServiceRecycleBox
+}
+
+func NewLeaderboardService(storage LeaderboardStorage, boxes recycleBox.ServiceRecycleBox) ServiceLeaderboard {
+	return &serviceLeaderboard{
+		storage: storage,
+		boxes:   boxes,
+	}
+}
+
+// Board ranks the users by the bottles they deposited or the points their deposits earned
+// in the current week or month (UTC) or of all time, in a box or in the boxes of an area.
+// The caller's own rank is returned even when it is not in the top.
+func (s *serviceLeaderboard) Board(ctx context.Context, dto *QueryDTO) (*BoardDTO, error) {
+	if err := queryValidator(dto); err != nil {
+		return nil, err
+	}
+	dto.Since = periodStart(dto.Period, time.Now().UTC())
+	if dto.BoxID != nil {
+		dto.BoxIDs = []int64{*dto.BoxID}
+	} else if dto.Latitude != nil {
+		ids, err := s.boxes.BoxIDsWithin(ctx, *dto.Latitude, *dto.Longitude, dto.Radius)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return &BoardDTO{Metric: dto.Metric, Period: dto.Period, Entries: []*Entry{}}, nil
+		}
+		dto.BoxIDs = ids
+	}
+
+	entries, err := s.storage.Top(dto)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []*Entry{}
+	}
+	me, err := s.storage.Rank(dto)
+	if err != nil {
+		return nil, err
+	}
+	return &BoardDTO{Metric: dto.Metric, Period: dto.Period, Entries: entries, Me: me}, nil
+}
+
+func (s *serviceLeaderboard) GetSettings(ctx context.Context, userID int64) (*Settings, error) {
+	return s.storage.GetSettings(userID)
+}
+
+// UpdateSettings opts the user in or out of appearing by name on the leaderboards
+func (s *serviceLeaderboard) UpdateSettings(ctx context.Context, userID int64, dto *SettingsDTO) (*Settings, error) {
+	if dto.Public != nil {
+		if err := s.storage.SetPublic(userID, *dto.Public); err != nil {
+			return nil, err
+		}
+	}
+	return s.storage.GetSettings(userID)
+}
+
+// periodStart returns the start of the current week (Monday) or month, nil for all time
+func periodStart(period string, now time.Time) *time.Time {
+	var start time.Time
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case PeriodWeek:
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PeriodMonth:
+		start = day.AddDate(0, 0, 1-day.Day())
+	default:
+		return nil
+	}
+	return &start
+}
+
+func queryValidator(dto *QueryDTO) error {
+	if dto.Metric == "" {
+		dto.Metric = MetricBottles
+	}
+	if dto.Metric != MetricBottles && dto.Metric != MetricPoints {
+		return customError.InvalidListQueryError
+	}
+	if dto.Period == "" {
+		dto.Period = PeriodWeek
+	}
+	if dto.Period != PeriodWeek && dto.Period != PeriodMonth && dto.Period != PeriodAll {
+		return customError.InvalidListQueryError
+	}
+	if dto.Limit == 0 {
+		dto.Limit = DefaultLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxLimit {
+		return customError.InvalidListQueryError
+	}
+	if dto.BoxID != nil && (dto.Latitude != nil || dto.Longitude != nil) {
+		return customError.InvalidListQueryError
+	}
+	if (dto.Latitude == nil) != (dto.Longitude == nil) {
+		return customError.InvalidLocationError
+	}
+	if dto.Latitude != nil {
+		if !utils.Finite(*dto.Latitude) || !utils.Finite(*dto.Longitude) ||
+			*dto.Latitude < -90 || *dto.Latitude > 90 || *dto.Longitude < -180 || *dto.Longitude > 180 {
+			return customError.InvalidLocationError
+		}
+		if dto.Radius == 0 {
+			dto.Radius = recycleBox.DefaultNearbyRadius
+		}
+		if !utils.Finite(dto.Radius) || dto.Radius < 0 || dto.Radius > recycleBox.MaxNearbyRadius {
+			return customError.InvalidLocationError
+		}
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package leaderboard
+
+type LeaderboardStorage interface {
+	// Top returns the first dto.Limit users of the board, users with equal values share a rank
+	Top(dto *QueryDTO) ([]*Entry, error)
+	// Rank returns the entry of dto.UserID, nil when the user has nothing in the board
+	Rank(dto *QueryDTO) (*Entry, error)
+	GetSettings(userID int64) (*Settings, error)
+	SetPublic(userID int64, public bool) error
+}
@@ -0,0 +1,16 @@
+package material
+
+type CreateMaterialDTO struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Points int64  `json:"points"`
+	Weight int64  `json:"weight"`
+}
+
+// UpdateMaterialDTO changes only the fields that are set
+type UpdateMaterialDTO struct {
+	Name   string `json:"name"`
+	Points *int64 `json:"points"`
+	Weight *int64 `json:"weight"`
+	Active *bool  `json:"active"`
+}
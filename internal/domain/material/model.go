@@ -0,0 +1,13 @@
+package material
+
+// DefaultMaterial is used when a deposit does not name a material, it is seeded by the migrations
+const DefaultMaterial = "bottle"
+
+// Material is a type of item boxes accept, Weight is the share of the box capacity one item takes
+type Material struct {
+	Code   string `json:"code"`
+	Name   string `json:"name"`
+	Points int64  `json:"points"`
+	Weight int64  `json:"weight"`
+	Active bool   `json:"active"`
+}
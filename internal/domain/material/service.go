@@ -0,0 +1,95 @@
+package material
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"regexp"
+	"strings"
+)
+
+var codePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+type ServiceMaterial interface {
+	ListMaterials(ctx context.Context) ([]*Material, error)
+	CreateMaterial(ctx context.Context, dto *CreateMaterialDTO) (*Material, error)
+	UpdateMaterial(ctx context.Context, code string, dto *UpdateMaterialDTO) (*Material, error)
+}
+
+type serviceMaterial struct {
+	storage MaterialStorage
+}
+
+func NewMaterialService(storage MaterialStorage) ServiceMaterial {
+	return &serviceMaterial{
+		storage: storage,
+	}
+}
+
+func (s *serviceMaterial) ListMaterials(ctx context.Context) ([]*Material, error) {
+	materials, err := s.storage.ListMaterials()
+	if err != nil {
+		return nil, err
+	}
+	if materials == nil {
+		materials = []*Material{}
+	}
+	return materials, nil
+}
+
+// CreateMaterial adds a material type to the catalog, boxes accept it only once it is added to them
+func (s *serviceMaterial) CreateMaterial(ctx context.Context, dto *CreateMaterialDTO) (*Material, error) {
+	m := &Material{
+		Code:   strings.ToLower(strings.TrimSpace(dto.Code)),
+		Name:   strings.TrimSpace(dto.Name),
+		Points: dto.Points,
+		Weight: dto.Weight,
+		Active: true,
+	}
+	if m.Weight == 0 {
+		m.Weight = 1
+	}
+	if err := validMaterial(m); err != nil {
+		return nil, err
+	}
+	if err := s.storage.CreateMaterial(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UpdateMaterial changes a material type, the new points apply to the next deposits only
+func (s *serviceMaterial) UpdateMaterial(ctx context.Context, code string, dto *UpdateMaterialDTO) (*Material, error) {
+	m, err := s.storage.GetMaterial(code)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(dto.Name); name != "" {
+		m.Name = name
+	}
+	if dto.Points != nil {
+		m.Points = *dto.Points
+	}
+	if dto.Weight != nil {
+		m.Weight = *dto.Weight
+	}
+	if dto.Active != nil {
+		m.Active = *dto.Active
+	}
+	if err := validMaterial(m); err != nil {
+		return nil, err
+	}
+	if m.Code == DefaultMaterial && !m.Active {
+		return nil, customError.InvalidMaterialError
+	}
+	if err := s.storage.UpdateMaterial(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func validMaterial(m *Material) error {
+	if !codePattern.MatchString(m.Code) || m.Name == "" || m.Points < 0 || m.Weight <= 0 {
+		return customError.InvalidMaterialError
+	}
+	return nil
+}
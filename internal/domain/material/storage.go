@@ -0,0 +1,8 @@
+package material
+
+type MaterialStorage interface {
+	ListMaterials() ([]*Material, error)
+	GetMaterial(code string) (*Material, error)
+	CreateMaterial(m *Material) error
+	UpdateMaterial(m *Material) error
+}
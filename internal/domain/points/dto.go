@@ -0,0 +1,40 @@
+package points
+
+import "time"
+
+// HistoryQueryDTO filters the ledger of a user, From is inclusive and To exclusive
+type HistoryQueryDTO struct {
+	UserID int64
+	From   *time.Time
+	To     *time.Time
+	Period string
+	Limit  int64
+	Offset int64
+}
+
+// HistoryEntry is a ledger entry with the title of its box
+type HistoryEntry struct {
+	*Transaction
+	BoxTitle *string `json:"box_title,omitempty"`
+}
+
+// PeriodTotal sums the entries of a day, a week (starting on Monday) or a month
+type PeriodTotal struct {
+	Period string `json:"period"`
+	Earned int64  `json:"earned"`
+	Spent  int64  `json:"spent"`
+	Net    int64  `json:"net"`
+}
+
+// HistoryDTO is the response of GET /me/points/history, the totals cover the whole date range
+type HistoryDTO struct {
+	Balance int64           `json:"balance"`
+	Items   []*HistoryEntry `json:"items"`
+	Total   int64           `json:"total"`
+	Limit   int64           `json:"limit"`
+	Offset  int64           `json:"offset"`
+	Earned  int64           `json:"earned"`
+	Spent   int64           `json:"spent"`
+	Period  string          `json:"period"`
+	Periods []*PeriodTotal  `json:"periods"`
+}
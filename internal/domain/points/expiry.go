@@ -0,0 +1,128 @@
+package points
+
+import (
+	"auth-api/pkg/client/mail"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// ExpiryNoticeAhead is how long before they expire users are told about their points,
+	// a user is notified at most once in that time
+	ExpiryNoticeAhead = 30 * 24 * time.Hour
+	mailTimeout       = 30 * time.Second
+)
+
+// ExpiryCutoff returns the time before which points earned have expired at now, points last 12 months
+func ExpiryCutoff(now time.Time) time.Time {
+	return now.UTC().AddDate(-1, 0, 0)
+}
+
+// RunMaintenance settles the expired deposit sessions, reports the balances that drifted from the ledger and
+// fixes them if fix is set, expires the points older than 12 months, warns the users whose points expire soon
+// and checks the balances again. Every step is safe to rerun: sessions are paid, points expire and users are
+// notified only once.
+func (s *servicePoints) RunMaintenance(ctx context.Context, now time.Time, fix bool) (*MaintenanceReport, error) {
+	report := &MaintenanceReport{}
+	var err error
+	if report.SettledSessions, err = s.sessions.SettleExpiredSessions(ctx, now); err != nil {
+		return nil, err
+	}
+	if report.Mismatches, err = s.Reconcile(ctx, fix); err != nil {
+		return nil, err
+	}
+	if report.Expired, err = s.ExpirePoints(ctx, now); err != nil {
+		return nil, err
+	}
+	for _, e := range report.Expired {
+		report.ExpiredPoints += e.Amount
+	}
+	if report.Notified, err = s.NotifyExpiring(ctx, now); err != nil {
+		return nil, err
+	}
+	if report.Remaining, err = s.Reconcile(ctx, false); err != nil {
+		return nil, err
+	}
+	if report.Mismatches == nil {
+		report.Mismatches = []*Mismatch{}
+	}
+	if report.Remaining == nil {
+		report.Remaining = []*Mismatch{}
+	}
+	return report, nil
+}
+
+// ExpirePoints writes an expiry entry for every user with points earned more than 12 months ago
+// that were not spent, the oldest points are the first spent
+func (s *servicePoints) ExpirePoints(ctx context.Context, now time.Time) ([]*Expiry, error) {
+	cutoff := ExpiryCutoff(now)
+	candidates, err := s.storage.ExpirablePoints(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	expired := []*Expiry{}
+	for _, c := range candidates {
+		amount, err := s.storage.ExpirePoints(c.UserID, cutoff, now.UTC())
+		if err != nil {
+			return nil, err
+		}
+		if amount > 0 {
+			expired = append(expired, &Expiry{UserID: c.UserID, Amount: amount})
+		}
+	}
+	return expired, nil
+}
+
+// NotifyExpiring emails the users whose points expire within ExpiryNoticeAhead and returns how many
+// were notified. A failed email is only logged and sent again by the next run.
+func (s *servicePoints) NotifyExpiring(ctx context.Context, now time.Time) (int, error) {
+	expiresBefore := now.UTC().Add(ExpiryNoticeAhead)
+	notices, err := s.storage.ExpiringNotices(ExpiryCutoff(expiresBefore), now.UTC().Add(-ExpiryNoticeAhead))
+	if err != nil {
+		return 0, err
+	}
+	notified := 0
+	for _, n := range notices {
+		n.ExpiresBefore = expiresBefore
+		m := &mail.Message{
+			To:      n.Email,
+			Subject: "Your points are about to expire",
+			Body: fmt.Sprintf("%d of your points expire before %s. Points expire 12 months after they are earned, "+
+				"redeem them for a reward before then.", n.Amount, expiresBefore.Format("2006-01-02")),
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, mailTimeout)
+		err := s.mailer.Send(sendCtx, m)
+		cancel()
+		if err != nil {
+			log.Printf("cannot send points expiry notice to user %d: %v", n.UserID, err)
+			continue
+		}
+		if err := s.storage.RecordExpiryNotice(n, now); err != nil {
+			return notified, err
+		}
+		notified++
+	}
+	return notified, nil
+}
+
+// Schedule runs RunMaintenance now and then every interval until ctx is done, failures are only logged
+func (s *servicePoints) Schedule(ctx context.Context, interval time.Duration, fix bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report, err := s.RunMaintenance(ctx, time.Now(), fix)
+		if err != nil {
+			log.Printf("points maintenance failed: %v", err)
+		} else {
+			log.Printf("points maintenance: %d session(s) settled, %d mismatch(es) found (fixed: %t), %d point(s) of %d user(s) expired, %d user(s) notified, %d mismatch(es) left",
+				report.SettledSessions, len(report.Mismatches), fix, report.ExpiredPoints, len(report.Expired), report.Notified, len(report.Remaining))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
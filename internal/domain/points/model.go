@@ -0,0 +1,63 @@
+package points
+
+import "time"
+
+const (
+	ReasonDeposit        = "deposit"
+	ReasonOpeningBalance = "opening_balance"
+	ReasonRedemption     = "redemption"
+	ReasonBadge          = "badge"
+	ReasonReferral       = "referral"
+	ReasonExpiry         = "expiry"
+	ReasonAdjustment     = "adjustment"
+)
+
+// Transaction is a single entry of the points ledger, awards are positive and debits negative
+type Transaction struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	BoxID     *int64    `json:"box_id,omitempty"`
+	Amount    int64     `json:"amount"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	// Rules are the point rules that added to the amount
+	Rules []*TransactionRule `json:"rules,omitempty"`
+}
+
+// TransactionRule is the share of a ledger entry a point rule contributed
+type TransactionRule struct {
+	RuleID int64 `json:"rule_id"`
+	Points int64 `json:"points"`
+}
+
+// Mismatch is a user whose cached users.points differs from the ledger total
+type Mismatch struct {
+	UserID      int64 `json:"user_id"`
+	Balance     int64 `json:"balance"`
+	LedgerTotal int64 `json:"ledger_total"`
+}
+
+// Expiry is the amount of points of a user that reached the end of their lifetime
+type Expiry struct {
+	UserID int64 `json:"user_id"`
+	Amount int64 `json:"amount"`
+}
+
+// ExpiryNotice warns a user that Amount points expire before ExpiresBefore
+type ExpiryNotice struct {
+	UserID        int64
+	Email         string
+	Amount        int64
+	ExpiresBefore time.Time
+}
+
+// MaintenanceReport is the outcome of a run of the points job, SettledSessions the expired deposit sessions
+// it closed, Mismatches the balances it found off the ledger (fixed if asked to) and Remaining the ones still off after it
+type MaintenanceReport struct {
+	SettledSessions int         `json:"settled_sessions"`
+	Mismatches      []*Mismatch `json:"mismatches"`
+	Expired         []*Expiry   `json:"expired"`
+	ExpiredPoints   int64       `json:"expired_points"`
+	Notified        int         `json:"notified"`
+	Remaining       []*Mismatch `json:"remaining"`
+}
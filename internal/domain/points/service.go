@@ -0,0 +1,140 @@
+package points
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/pkg/client/mail"
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+type ServicePoints interface {
+	Reconcile(ctx context.Context, fix bool) ([]*Mismatch, error)
+	History(ctx context.Context, dto *HistoryQueryDTO) (*HistoryDTO, error)
+	RunMaintenance(ctx context.Context, now time.Time, fix bool) (*MaintenanceReport, error)
+	ExpirePoints(ctx context.Context, now time.Time) ([]*Expiry, error)
+	NotifyExpiring(ctx context.Context, now time.Time) (int, error)
+	Schedule(ctx context.Context, interval time.Duration, fix bool)
+	ResetPoints(ctx context.Context, userID int64) (*Transaction, error)
+}
+
+// SessionSettler closes the deposit sessions that expired without the box closing them,
+// the points job runs it first so their bottles are paid before the balances are checked
+type SessionSettler interface {
+	SettleExpiredSessions(ctx context.Context, now time.Time) (int, error)
+}
+
+type servicePoints struct {
+	storage  PointsStorage
+	mailer   mail.Sender
+	sessions SessionSettler
+}
+
+func NewPointsService(storage PointsStorage, mailer mail.Sender, sessions SessionSettler) ServicePoints {
+	return &servicePoints{
+		storage:  storage,
+		mailer:   mailer,
+		sessions: sessions,
+	}
+}
+
+// Reconcile compares users.points with the ledger and, if fix is set, resets the balance to the ledger total
+func (s *servicePoints) Reconcile(ctx context.Context, fix bool) ([]*Mismatch, error) {
+	mismatches, err := s.storage.FindMismatches()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mismatches {
+		log.Printf("points mismatch: user %d has %d, ledger total %d", m.UserID, m.Balance, m.LedgerTotal)
+		if !fix {
+			continue
+		}
+		if err := s.storage.FixBalance(m.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return mismatches, nil
+}
+
+// ResetPoints sets the balance of the user to zero through an adjustment entry of the ledger
+func (s *servicePoints) ResetPoints(ctx context.Context, userID int64) (*Transaction, error) {
+	t, err := s.storage.ResetPoints(userID, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("points reset: user %d, adjustment %d", userID, t.Amount)
+	return t, nil
+}
+
+// History returns a page of the awards and debits of the user with the totals of the date range per period
+func (s *servicePoints) History(ctx context.Context, dto *HistoryQueryDTO) (*HistoryDTO, error) {
+	if dto.Limit == 0 {
+		dto.Limit = DefaultListLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxListLimit || dto.Offset < 0 {
+		return nil, customError.InvalidListQueryError
+	}
+	if dto.Period == "" {
+		dto.Period = PeriodMonth
+	}
+	if dto.Period != PeriodDay && dto.Period != PeriodWeek && dto.Period != PeriodMonth {
+		return nil, customError.InvalidListQueryError
+	}
+	if dto.From != nil && dto.To != nil && !dto.To.After(*dto.From) {
+		return nil, customError.InvalidListQueryError
+	}
+
+	balance, err := s.storage.GetBalance(dto.UserID)
+	if err != nil {
+		return nil, err
+	}
+	items, total, err := s.storage.ListTransactions(dto)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []*HistoryEntry{}
+	}
+	ids := make([]int64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	rules, err := s.storage.TransactionRules(ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		item.Rules = rules[item.ID]
+	}
+	periods, err := s.storage.PeriodTotals(dto)
+	if err != nil {
+		return nil, err
+	}
+	if periods == nil {
+		periods = []*PeriodTotal{}
+	}
+
+	history := &HistoryDTO{
+		Balance: balance,
+		Items:   items,
+		Total:   total,
+		Limit:   dto.Limit,
+		Offset:  dto.Offset,
+		Period:  dto.Period,
+		Periods: periods,
+	}
+	for _, p := range periods {
+		history.Earned += p.Earned
+		history.Spent += p.Spent
+	}
+	return history, nil
+}
@@ -0,0 +1,26 @@
+package points
+
+import "time"
+
+type PointsStorage interface {
+	FindMismatches() ([]*Mismatch, error)
+	FixBalance(userID int64) error
+	GetBalance(userID int64) (int64, error)
+	// ListTransactions returns a page of the ledger of the user, newest first, and the number of matching entries
+	ListTransactions(dto *HistoryQueryDTO) ([]*HistoryEntry, int64, error)
+	// TransactionRules returns the rule contributions of the entries by transaction ID
+	TransactionRules(ids []int64) (map[int64][]*TransactionRule, error)
+	// PeriodTotals sums the matching entries per dto.Period, newest first
+	PeriodTotals(dto *HistoryQueryDTO) ([]*PeriodTotal, error)
+	// ExpirablePoints returns the users with points earned before cutoff that were not spent yet.
+	// Debits use the oldest points first, so that is what was earned before cutoff minus every debit.
+	ExpirablePoints(cutoff time.Time) ([]*Expiry, error)
+	// ExpirePoints writes an expiry entry for what ExpirablePoints returns for the user, in one transaction
+	ExpirePoints(userID int64, cutoff time.Time, now time.Time) (int64, error)
+	// ExpiringNotices returns the users with points earned before cutoff who were not notified since notifiedSince
+	ExpiringNotices(cutoff time.Time, notifiedSince time.Time) ([]*ExpiryNotice, error)
+	RecordExpiryNotice(n *ExpiryNotice, now time.Time) error
+	// ResetPoints writes an adjustment entry that brings the balance of the user to zero, in one transaction.
+	// Nothing is written when the balance is already zero.
+	ResetPoints(userID int64, now time.Time) (*Transaction, error)
+}
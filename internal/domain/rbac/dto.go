@@ -0,0 +1,9 @@
+package rbac
+
+type AssignRoleDTO struct {
+	Role string `json:"role"`
+}
+
+type RoleTwoFactorDTO struct {
+	Required bool `json:"required"`
+}
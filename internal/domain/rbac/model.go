@@ -0,0 +1,29 @@
+package rbac
+
+// Roles seeded by the migrations, more can be added to the roles table
+const (
+	RoleAdmin     = "admin"
+	RoleCollector = "collector"
+	RoleUser      = "user"
+	RolePartner   = "partner"
+)
+
+const (
+	PermissionBoxCreate      = "box:create"
+	PermissionBoxUpdate      = "box:update"
+	PermissionBoxCollect     = "box:collect"
+	PermissionBoxDeposit     = "box:deposit"
+	PermissionUserManage     = "user:manage"
+	PermissionMaterialManage = "material:manage"
+	PermissionRuleManage     = "rule:manage"
+	PermissionRewardManage   = "reward:manage"
+	PermissionRewardConsume  = "reward:consume"
+	PermissionBadgeManage    = "badge:manage"
+)
+
+type Role struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	Permissions      []string `json:"permissions"`
+	RequireTwoFactor bool     `json:"require_2fa"`
+}
@@ -0,0 +1,89 @@
+package rbac
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"errors"
+	"slices"
+)
+
+type ServiceRBAC interface {
+	UserRole(ctx context.Context, userID int64) (string, bool, error)
+	RoleHasPermissions(ctx context.Context, role string, permissions ...string) (bool, error)
+	ListRoles(ctx context.Context) ([]*Role, error)
+	AssignRole(ctx context.Context, userID int64, dto *AssignRoleDTO) error
+	SetRoleTwoFactor(ctx context.Context, role string, dto *RoleTwoFactorDTO) error
+	TwoFactorMissing(ctx context.Context, userID int64, role string) (bool, error)
+}
+
+type serviceRBAC struct {
+	storage RBACStorage
+}
+
+func NewRBACService(storage RBACStorage) ServiceRBAC {
+	return &serviceRBAC{
+		storage: storage,
+	}
+}
+
+// UserRole returns the current role of the user and whether the account is blocked,
+// it is read on every request so role changes and blocks apply at once
+func (s *serviceRBAC) UserRole(ctx context.Context, userID int64) (string, bool, error) {
+	return s.storage.GetUserRole(userID)
+}
+
+// RoleHasPermissions reports whether the role grants all the permissions
+func (s *serviceRBAC) RoleHasPermissions(ctx context.Context, role string, permissions ...string) (bool, error) {
+	r, err := s.storage.GetRole(role)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if !slices.Contains(r.Permissions, p) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (s *serviceRBAC) ListRoles(ctx context.Context) ([]*Role, error) {
+	return s.storage.ListRoles()
+}
+
+// AssignRole changes the role of the user
+func (s *serviceRBAC) AssignRole(ctx context.Context, userID int64, dto *AssignRoleDTO) error {
+	if _, err := s.storage.GetRole(dto.Role); err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.UnknownRoleError
+		}
+		return err
+	}
+	return s.storage.SetUserRole(userID, dto.Role)
+}
+
+// SetRoleTwoFactor makes two-factor authentication mandatory (or optional) for the users of a role
+func (s *serviceRBAC) SetRoleTwoFactor(ctx context.Context, role string, dto *RoleTwoFactorDTO) error {
+	if err := s.storage.SetRoleRequireTwoFactor(role, dto.Required); err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.UnknownRoleError
+		}
+		return err
+	}
+	return nil
+}
+
+// TwoFactorMissing reports whether the role requires two-factor authentication the user has not enabled
+func (s *serviceRBAC) TwoFactorMissing(ctx context.Context, userID int64, role string) (bool, error) {
+	r, err := s.storage.GetRole(role)
+	if err != nil {
+		return false, err
+	}
+	if !r.RequireTwoFactor {
+		return false, nil
+	}
+	enabled, err := s.storage.UserHasTwoFactor(userID)
+	if err != nil {
+		return false, err
+	}
+	return !enabled, nil
+}
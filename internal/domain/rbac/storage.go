@@ -0,0 +1,11 @@
+package rbac
+
+type RBACStorage interface {
+	// GetUserRole returns the role of the user and whether the account is blocked
+	GetUserRole(userID int64) (string, bool, error)
+	SetUserRole(userID int64, role string) error
+	GetRole(name string) (*Role, error)
+	ListRoles() ([]*Role, error)
+	SetRoleRequireTwoFactor(name string, required bool) error
+	UserHasTwoFactor(userID int64) (bool, error)
+}
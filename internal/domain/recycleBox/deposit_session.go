@@ -0,0 +1,118 @@
+package recycleBox
+
+import (
+	"auth-api/internal/domain/material"
+	"auth-api/internal/domain/rule"
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// DepositSessionClaimTTL is how long the code shown by the box can be claimed
+	DepositSessionClaimTTL = time.Minute * 2
+	// DepositSessionTTL is how long the box can report bottles after the claim
+	DepositSessionTTL = time.Minute * 10
+
+	sessionCodeLength = 8
+	sessionQRPrefix   = "recyclebox:session:"
+)
+
+// OpenDepositSession starts a session on the box, the returned code is the only way to claim it
+func (s *serviceRecycleBox) OpenDepositSession(ctx context.Context, boxId int64) (*OpenedDepositSessionDTO, error) {
+	code, err := utils.RandomCode(sessionCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	ds := &DepositSession{
+		BoxID:     boxId,
+		CodeHash:  utils.HashToken(code),
+		Status:    DepositSessionOpen,
+		CreatedAt: now,
+		ExpiresAt: now.Add(DepositSessionClaimTTL),
+	}
+	if err := s.storage.CreateDepositSession(ds); err != nil {
+		return nil, err
+	}
+	return &OpenedDepositSessionDTO{DepositSession: ds, Code: code, QRPayload: sessionQRPrefix + code}, nil
+}
+
+// ClaimDepositSession binds the session to the user, a session can be claimed only once.
+// The code may be typed by the user or read from the QR payload.
+func (s *serviceRecycleBox) ClaimDepositSession(ctx context.Context, userId int64, dto *ClaimDepositSessionDTO) (*DepositSession, error) {
+	code := strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(dto.Code, sessionQRPrefix)))
+	if code == "" {
+		return nil, customError.DepositSessionUnavailableError
+	}
+	now := time.Now().UTC()
+	return s.storage.ClaimDepositSession(utils.HashToken(code), userId, now, now.Add(DepositSessionTTL))
+}
+
+// GetDepositSession returns the session to the user who claimed it
+func (s *serviceRecycleBox) GetDepositSession(ctx context.Context, sessionId int64, userId int64) (*DepositSession, error) {
+	ds, err := s.storage.GetDepositSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if ds.UserID == nil || *ds.UserID != userId {
+		return nil, customError.NotFoundError
+	}
+	return ds, nil
+}
+
+// AddSessionBottle counts an item reported by the box in its claimed session, the points of its
+// material are accrued and awarded on close
+func (s *serviceRecycleBox) AddSessionBottle(ctx context.Context, sessionId int64, boxId int64, dto *SessionBottleDTO) (*DepositSession, error) {
+	if dto.Material == "" {
+		dto.Material = material.DefaultMaterial
+	}
+	return s.storage.AddSessionBottle(sessionId, boxId, dto.Material, time.Now().UTC())
+}
+
+// CloseDepositSession ends the session and awards the points for all its bottles at once,
+// closing an unclaimed session just cancels it. The point rules treat the session as one deposit.
+func (s *serviceRecycleBox) CloseDepositSession(ctx context.Context, sessionId int64, boxId int64) (*DepositSession, error) {
+	return s.closeDepositSession(ctx, sessionId, boxId, time.Now().UTC())
+}
+
+// SettleExpiredSessions closes the sessions the box did not close before they expired: the bottles
+// of a claimed session are paid like on close, an open session is cancelled. It returns how many
+// sessions were closed. A session the box closes meanwhile is skipped.
+func (s *serviceRecycleBox) SettleExpiredSessions(ctx context.Context, now time.Time) (int, error) {
+	sessions, err := s.storage.ExpiredDepositSessions(now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	settled := 0
+	for _, ds := range sessions {
+		if _, err := s.closeDepositSession(ctx, ds.ID, ds.BoxID, now.UTC()); err != nil {
+			if errors.Is(err, customError.DepositSessionUnavailableError) {
+				continue
+			}
+			return settled, err
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+func (s *serviceRecycleBox) closeDepositSession(ctx context.Context, sessionId int64, boxId int64, now time.Time) (*DepositSession, error) {
+	award := func(usage rule.UsageReader, box *RecycleBox, userId int64, basePoints int64) (*rule.Award, error) {
+		return s.evaluateRules(ctx, usage, box, userId, basePoints)
+	}
+	ds, err := s.storage.CloseDepositSession(sessionId, boxId, now, award)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return nil, customError.DepositSessionUnavailableError
+		}
+		return nil, err
+	}
+	if ds.UserID != nil && ds.Bottles > 0 {
+		s.afterDeposit(ctx, *ds.UserID)
+	}
+	return ds, nil
+}
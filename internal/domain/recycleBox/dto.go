@@ -1,13 +1,94 @@
 package recycleBox
 
+import "auth-api/internal/domain/device"
+
 type CreateRecycleBoxDTO struct {
-	Title    string `json:"title"`
-	Address  string `json:"address"`
-	Capacity int64  `json:"capacity"`
+	Title     string   `json:"title"`
+	Address   string   `json:"address"`
+	Capacity  int64    `json:"capacity"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Materials []string `json:"materials"` // the default material when empty
 }
 type UpdateRecycleBoxDTO struct {
-	Title    string `json:"title"`
-	Address  string `json:"address"`
-	Capacity int64  `json:"capacity"`
-	Count    int64  `json:"count"`
+	Title     string   `json:"title"`
+	Address   string   `json:"address"`
+	Capacity  int64    `json:"capacity"`
+	Count     int64    `json:"count"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// ListRecycleBoxDTO holds the filters of GET /recyclebox
+type ListRecycleBoxDTO struct {
+	Query  string
+	Full   *bool
+	Sort   string
+	Order  string
+	Limit  int64
+	Offset int64
+}
+
+type RecycleBoxListDTO struct {
+	Items  []*RecycleBox `json:"items"`
+	Total  int64         `json:"total"`
+	Limit  int64         `json:"limit"`
+	Offset int64         `json:"offset"`
+}
+
+// NearbyRecycleBoxDTO holds the parameters of GET /recyclebox/nearby, Radius is in meters
+type NearbyRecycleBoxDTO struct {
+	Latitude    float64
+	Longitude   float64
+	Radius      float64
+	ExcludeFull bool
+	Limit       int64
+}
+
+// BoundingBoxDTO is the coordinate range used to prefilter boxes in storage
+type BoundingBoxDTO struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+	ExcludeFull  bool
+}
+
+// ListCollectionsDTO holds the parameters of GET /recyclebox/{id}/collections
+type ListCollectionsDTO struct {
+	BoxID  int64
+	Limit  int64
+	Offset int64
+}
+
+// CreatedRecycleBoxDTO is the new box with the credentials of its device, they are shown only once
+type CreatedRecycleBoxDTO struct {
+	*RecycleBox
+	Device *device.DeviceCredentialsDTO `json:"device"`
+}
+
+// DeviceDepositDTO is the body of POST /device/deposits, the item is only counted:
+// points are awarded through deposit sessions, which the user claims
+type DeviceDepositDTO struct {
+	Material string `json:"material"`
+}
+
+// SessionBottleDTO is the body of POST /device/sessions/{id}/bottles
+type SessionBottleDTO struct {
+	Material string `json:"material"`
+}
+
+type BoxMaterialsDTO struct {
+	Materials []string `json:"materials"`
+}
+
+// OpenedDepositSessionDTO is returned to the box, it shows the code or the QR payload to the user
+type OpenedDepositSessionDTO struct {
+	*DepositSession
+	Code      string `json:"code"`
+	QRPayload string `json:"qr_payload"`
+}
+
+type ClaimDepositSessionDTO struct {
+	Code string `json:"code"`
 }
@@ -0,0 +1,37 @@
+package recycleBox
+
+import (
+	"auth-api/internal/utils"
+	"math"
+)
+
+// BoundingBox returns the coordinate range that contains every point within radius meters.
+// It is only a prefilter, the exact check is done with Distance.
+func BoundingBox(lat, lng, radius float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radius / utils.EarthRadius * 180 / math.Pi
+	minLat, maxLat = math.Max(-90, lat-dLat), math.Min(90, lat+dLat)
+	cos := math.Cos(lat * math.Pi / 180)
+	if cos < 1e-6 || maxLat == 90 || minLat == -90 {
+		return minLat, maxLat, -180, 180
+	}
+	dLng := dLat / cos
+	minLng, maxLng = lng-dLng, lng+dLng
+	if minLng < -180 || maxLng > 180 {
+		// The circle crosses the antimeridian, do not filter by longitude
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+func validCoordinates(lat, lng *float64) bool {
+	if lat == nil && lng == nil {
+		return true
+	}
+	if lat == nil || lng == nil {
+		return false
+	}
+	if !utils.Finite(*lat) || !utils.Finite(*lng) {
+		return false
+	}
+	return *lat >= -90 && *lat <= 90 && *lng >= -180 && *lng <= 180
+}
@@ -1,9 +1,51 @@
 package recycleBox
 
+import "time"
+
 type RecycleBox struct {
-	Id       int64  `json:"id"`
-	Title    string `json:"title"`
-	Address  string `json:"address"`
-	Capacity int64  `json:"capacity"`
-	Count    int64  `json:"count"`
+	Id        int64    `json:"id"`
+	Title     string   `json:"title"`
+	Address   string   `json:"address"`
+	Capacity  int64    `json:"capacity"`
+	Count     int64    `json:"count"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Materials []string `json:"materials"`
+}
+
+// RecycleBoxDistance is a recycle box found by a nearby search
+type RecycleBoxDistance struct {
+	*RecycleBox
+	Distance float64 `json:"distance"`
+}
+
+// Collection is a record of a collector emptying a recycle box
+type Collection struct {
+	ID          int64     `json:"id"`
+	BoxID       int64     `json:"box_id"`
+	CollectorID int64     `json:"collector_id"`
+	Bottles     int64     `json:"bottles"`
+	CollectedAt time.Time `json:"collected_at"`
+}
+
+const (
+	DepositSessionOpen    = "open"
+	DepositSessionClaimed = "claimed"
+	DepositSessionClosed  = "closed"
+)
+
+// DepositSession links the bottles reported by a box to the user who claimed the session,
+// the points are awarded in one batch when the box closes it
+type DepositSession struct {
+	ID        int64      `json:"id"`
+	BoxID     int64      `json:"box_id"`
+	UserID    *int64     `json:"user_id"`
+	CodeHash  string     `json:"-"`
+	Status    string     `json:"status"`
+	Bottles   int64      `json:"bottles"`
+	Points    int64      `json:"points"` // accrued by the bottles, awarded on close
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	ClaimedAt *time.Time `json:"claimed_at"`
+	ClosedAt  *time.Time `json:"closed_at"`
 }
@@ -1,24 +1,64 @@
 package recycleBox
 
 import (
+	"auth-api/internal/domain/badge"
+	"auth-api/internal/domain/device"
+	"auth-api/internal/domain/material"
+	"auth-api/internal/domain/referral"
+	"auth-api/internal/domain/rule"
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
 	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	DefaultNearbyRadius = 1000.0  // meters
+	MaxNearbyRadius     = 50000.0 // meters
 )
 
 type ServiceRecycleBox interface {
 	GetRecycleBox(ctx context.Context, id int64) (*RecycleBox, error)
-	CreateRecycleBox(ctx context.Context, dto *CreateRecycleBoxDTO) (*RecycleBox, error)
+	ListRecycleBoxes(ctx context.Context, dto *ListRecycleBoxDTO) (*RecycleBoxListDTO, error)
+	NearbyRecycleBoxes(ctx context.Context, dto *NearbyRecycleBoxDTO) ([]*RecycleBoxDistance, error)
+	BoxIDsWithin(ctx context.Context, lat, lng, radius float64) ([]int64, error)
+	CreateRecycleBox(ctx context.Context, dto *CreateRecycleBoxDTO) (*CreatedRecycleBoxDTO, error)
+	IssueDeviceCredentials(ctx context.Context, boxId int64) (*device.DeviceCredentialsDTO, error)
+	SetBoxMaterials(ctx context.Context, boxId int64, dto *BoxMaterialsDTO) (*RecycleBox, error)
 	UpdateRecycleBox(ctx context.Context, id int64, dto *UpdateRecycleBoxDTO) (*RecycleBox, error)
 	AddBottle(ctx context.Context, boxId int64) (*RecycleBox, error)
-	AddBottleWithPoints(ctx context.Context, boxId int64, userId int64) (*RecycleBox, error)
+	DeviceDeposit(ctx context.Context, boxId int64, dto *DeviceDepositDTO) (*RecycleBox, error)
+	CollectRecycleBox(ctx context.Context, boxId int64, collectorId int64) (*Collection, error)
+	ListCollections(ctx context.Context, dto *ListCollectionsDTO) ([]*Collection, error)
+	OpenDepositSession(ctx context.Context, boxId int64) (*OpenedDepositSessionDTO, error)
+	ClaimDepositSession(ctx context.Context, userId int64, dto *ClaimDepositSessionDTO) (*DepositSession, error)
+	GetDepositSession(ctx context.Context, sessionId int64, userId int64) (*DepositSession, error)
+	AddSessionBottle(ctx context.Context, sessionId int64, boxId int64, dto *SessionBottleDTO) (*DepositSession, error)
+	CloseDepositSession(ctx context.Context, sessionId int64, boxId int64) (*DepositSession, error)
+	SettleExpiredSessions(ctx context.Context, now time.Time) (int, error)
 }
 
 type serviceRecycleBox struct {
-	storage RecycleBoxStorage
+	storage   RecycleBoxStorage
+	devices   device.ServiceDevice
+	rules     rule.ServiceRule
+	badges    badge.ServiceBadge
+	referrals referral.ServiceReferral
 }
 
-func NewRecycleBoxService(storage RecycleBoxStorage) ServiceRecycleBox {
+func NewRecycleBoxService(storage RecycleBoxStorage, devices device.ServiceDevice, rules rule.ServiceRule, badges badge.ServiceBadge, referrals referral.ServiceReferral) ServiceRecycleBox {
 	return &serviceRecycleBox{
-		storage: storage,
+		storage:   storage,
+		devices:   devices,
+		rules:     rules,
+		badges:    badges,
+		referrals: referrals,
 	}
 }
 
@@ -27,28 +67,220 @@ func (s *serviceRecycleBox) GetRecycleBox(ctx context.Context, id int64) (*Recyc
 	return s.storage.GetRecycleBox(id)
 }
 
-// CreateRecycleBox creates a new recycle box
-func (s *serviceRecycleBox) CreateRecycleBox(ctx context.Context, dto *CreateRecycleBoxDTO) (*RecycleBox, error) {
-	return s.storage.CreateRecycleBox(dto)
+// ListRecycleBoxes returns a page of recycle boxes matching the filters
+func (s *serviceRecycleBox) ListRecycleBoxes(ctx context.Context, dto *ListRecycleBoxDTO) (*RecycleBoxListDTO, error) {
+	if err := listValidator(dto); err != nil {
+		return nil, err
+	}
+	boxes, total, err := s.storage.ListRecycleBoxes(dto)
+	if err != nil {
+		return nil, err
+	}
+	if boxes == nil {
+		boxes = []*RecycleBox{}
+	}
+	return &RecycleBoxListDTO{Items: boxes, Total: total, Limit: dto.Limit, Offset: dto.Offset}, nil
+}
+
+// NearbyRecycleBoxes returns the boxes within the radius ordered by distance
+func (s *serviceRecycleBox) NearbyRecycleBoxes(ctx context.Context, dto *NearbyRecycleBoxDTO) ([]*RecycleBoxDistance, error) {
+	if err := nearbyValidator(dto); err != nil {
+		return nil, err
+	}
+	minLat, maxLat, minLng, maxLng := BoundingBox(dto.Latitude, dto.Longitude, dto.Radius)
+	boxes, err := s.storage.RecycleBoxesInBoundingBox(&BoundingBoxDTO{
+		MinLatitude:  minLat,
+		MaxLatitude:  maxLat,
+		MinLongitude: minLng,
+		MaxLongitude: maxLng,
+		ExcludeFull:  dto.ExcludeFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	nearby := []*RecycleBoxDistance{}
+	for _, rb := range boxes {
+		d := utils.Distance(dto.Latitude, dto.Longitude, *rb.Latitude, *rb.Longitude)
+		if d <= dto.Radius {
+			nearby = append(nearby, &RecycleBoxDistance{RecycleBox: rb, Distance: d})
+		}
+	}
+	sort.Slice(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+	if int64(len(nearby)) > dto.Limit {
+		nearby = nearby[:dto.Limit]
+	}
+	return nearby, nil
+}
+
+// BoxIDsWithin returns the IDs of every box within radius meters of the point, full boxes included
+func (s *serviceRecycleBox) BoxIDsWithin(ctx context.Context, lat, lng, radius float64) ([]int64, error) {
+	minLat, maxLat, minLng, maxLng := BoundingBox(lat, lng, radius)
+	boxes, err := s.storage.RecycleBoxesInBoundingBox(&BoundingBoxDTO{
+		MinLatitude:  minLat,
+		MaxLatitude:  maxLat,
+		MinLongitude: minLng,
+		MaxLongitude: maxLng,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, rb := range boxes {
+		if utils.Distance(lat, lng, *rb.Latitude, *rb.Longitude) <= radius {
+			ids = append(ids, rb.Id)
+		}
+	}
+	return ids, nil
+}
+
+// CreateRecycleBox creates a new recycle box and issues the credentials of its device
+func (s *serviceRecycleBox) CreateRecycleBox(ctx context.Context, dto *CreateRecycleBoxDTO) (*CreatedRecycleBoxDTO, error) {
+	if !validCoordinates(dto.Latitude, dto.Longitude) {
+		return nil, customError.InvalidLocationError
+	}
+	if len(dto.Materials) == 0 {
+		dto.Materials = []string{material.DefaultMaterial}
+	}
+	rb, err := s.storage.CreateRecycleBox(dto)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := s.devices.IssueCredentials(ctx, rb.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedRecycleBoxDTO{RecycleBox: rb, Device: credentials}, nil
+}
+
+// IssueDeviceCredentials replaces the device secret of the box, e.g. when the hardware is swapped
+func (s *serviceRecycleBox) IssueDeviceCredentials(ctx context.Context, boxId int64) (*device.DeviceCredentialsDTO, error) {
+	if _, err := s.storage.GetRecycleBox(boxId); err != nil {
+		return nil, err
+	}
+	return s.devices.IssueCredentials(ctx, boxId)
 }
 
 // UpdateRecycleBox updates an existing recycle box's details
 func (s *serviceRecycleBox) UpdateRecycleBox(ctx context.Context, id int64, dto *UpdateRecycleBoxDTO) (*RecycleBox, error) {
+	if !validCoordinates(dto.Latitude, dto.Longitude) {
+		return nil, customError.InvalidLocationError
+	}
 	return s.storage.UpdateRecycleBox(id, dto)
 }
 
+// SetBoxMaterials replaces the material types the box accepts
+func (s *serviceRecycleBox) SetBoxMaterials(ctx context.Context, boxId int64, dto *BoxMaterialsDTO) (*RecycleBox, error) {
+	if len(dto.Materials) == 0 {
+		return nil, customError.InvalidMaterialError
+	}
+	return s.storage.SetBoxMaterials(boxId, dto.Materials)
+}
+
 // AddBottle increments bottle count in the recycle box without awarding points
 func (s *serviceRecycleBox) AddBottle(ctx context.Context, boxId int64) (*RecycleBox, error) {
-	return s.storage.AddBottle(boxId)
+	return s.storage.AddBottle(boxId, material.DefaultMaterial)
+}
+
+// DeviceDeposit counts an item reported by the device of the box without points. The device cannot
+// name who deposited, points are awarded only through a deposit session the user claimed.
+func (s *serviceRecycleBox) DeviceDeposit(ctx context.Context, boxId int64, dto *DeviceDepositDTO) (*RecycleBox, error) {
+	if dto.Material == "" {
+		dto.Material = material.DefaultMaterial
+	}
+	return s.storage.AddBottle(boxId, dto.Material)
 }
 
-// AddBottleWithPoints increments bottle count in the recycle box and awards points to the user
-func (s *serviceRecycleBox) AddBottleWithPoints(ctx context.Context, boxId int64, userId int64) (*RecycleBox, error) {
-	// Attempt to add a bottle to the recycle box
-	rb, err := s.storage.AddBottleWithPoints(boxId, userId)
+// evaluateRules returns the extra points the point rules add to a deposit of basePoints at the box,
+// rb is the box state from before the deposit
+func (s *serviceRecycleBox) evaluateRules(ctx context.Context, usage rule.UsageReader, rb *RecycleBox, userId int64, basePoints int64) (*rule.Award, error) {
+	return s.rules.Evaluate(ctx, &rule.Deposit{
+		UserID:     userId,
+		BoxID:      rb.Id,
+		Latitude:   rb.Latitude,
+		Longitude:  rb.Longitude,
+		Count:      rb.Count,
+		Capacity:   rb.Capacity,
+		BasePoints: basePoints,
+		At:         time.Now().UTC(),
+	}, usage)
+}
+
+// afterDeposit awards the badges the deposits of the user reached and settles their referral.
+// The deposit is already counted, so a failure is only logged, both are retried with the next deposit.
+func (s *serviceRecycleBox) afterDeposit(ctx context.Context, userId int64) {
+	if _, err := s.badges.OnDeposit(ctx, userId); err != nil {
+		log.Printf("cannot award badges to user %d: %v", userId, err)
+	}
+	if _, err := s.referrals.OnDeposit(ctx, userId); err != nil {
+		log.Printf("cannot settle referral of user %d: %v", userId, err)
+	}
+}
+
+// CollectRecycleBox empties the recycle box and records who removed how many bottles
+func (s *serviceRecycleBox) CollectRecycleBox(ctx context.Context, boxId int64, collectorId int64) (*Collection, error) {
+	return s.storage.FlushRecycleBox(boxId, collectorId)
+}
+
+// ListCollections returns the collection history of a recycle box, newest first
+func (s *serviceRecycleBox) ListCollections(ctx context.Context, dto *ListCollectionsDTO) ([]*Collection, error) {
+	if dto.Limit == 0 {
+		dto.Limit = DefaultListLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxListLimit || dto.Offset < 0 {
+		return nil, customError.InvalidListQueryError
+	}
+	if _, err := s.storage.GetRecycleBox(dto.BoxID); err != nil {
+		return nil, err
+	}
+	collections, err := s.storage.ListCollections(dto)
 	if err != nil {
 		return nil, err
 	}
+	if collections == nil {
+		collections = []*Collection{}
+	}
+	return collections, nil
+}
 
-	return rb, nil
+func listValidator(dto *ListRecycleBoxDTO) error {
+	if dto.Limit == 0 {
+		dto.Limit = DefaultListLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxListLimit || dto.Offset < 0 {
+		return customError.InvalidListQueryError
+	}
+	// Unknown sort fields are rejected by the storage, which maps the fields to columns
+	if dto.Sort == "" {
+		dto.Sort = "id"
+	}
+	dto.Order = strings.ToLower(dto.Order)
+	if dto.Order == "" {
+		dto.Order = "asc"
+	}
+	if dto.Order != "asc" && dto.Order != "desc" {
+		return customError.InvalidListQueryError
+	}
+	dto.Query = strings.TrimSpace(dto.Query)
+	return nil
+}
+
+func nearbyValidator(dto *NearbyRecycleBoxDTO) error {
+	if !validCoordinates(&dto.Latitude, &dto.Longitude) {
+		return customError.InvalidLocationError
+	}
+	if dto.Radius == 0 {
+		dto.Radius = DefaultNearbyRadius
+	}
+	if !utils.Finite(dto.Radius) || dto.Radius < 0 || dto.Radius > MaxNearbyRadius {
+		return customError.InvalidLocationError
+	}
+	if dto.Limit == 0 {
+		dto.Limit = DefaultListLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxListLimit {
+		return customError.InvalidListQueryError
+	}
+	return nil
 }
@@ -1,10 +1,36 @@
 package recycleBox
 
+import (
+	"auth-api/internal/domain/rule"
+	"time"
+)
+
 type RecycleBoxStorage interface {
 	GetRecycleBox(int64) (*RecycleBox, error)
 	CreateRecycleBox(*CreateRecycleBoxDTO) (*RecycleBox, error)
 	UpdateRecycleBox(int64, *UpdateRecycleBoxDTO) (*RecycleBox, error)
-	FlushRecycleBox(int64) (*RecycleBox, error)
-	AddBottle(int64) (*RecycleBox, error)
-	AddBottleWithPoints(int64, int64) (*RecycleBox, error)
+	FlushRecycleBox(int64, int64) (*Collection, error)
+	ListCollections(*ListCollectionsDTO) ([]*Collection, error)
+	SetBoxMaterials(int64, []string) (*RecycleBox, error)
+	// AddBottle takes the box ID and the material code, the item is counted without points
+	AddBottle(int64, string) (*RecycleBox, error)
+	// ListRecycleBoxes returns InvalidListQueryError for a sort field it has no column for
+	ListRecycleBoxes(*ListRecycleBoxDTO) ([]*RecycleBox, int64, error)
+	RecycleBoxesInBoundingBox(*BoundingBoxDTO) ([]*RecycleBox, error)
+	CreateDepositSession(*DepositSession) error
+	GetDepositSession(int64) (*DepositSession, error)
+	// ClaimDepositSession binds the open, unexpired session with the code hash to the user
+	ClaimDepositSession(codeHash string, userId int64, now time.Time, expiresAt time.Time) (*DepositSession, error)
+	// AddSessionBottle counts a bottle in the box and in its claimed, unexpired session
+	AddSessionBottle(sessionId int64, boxId int64, material string, now time.Time) (*DepositSession, error)
+	// CloseDepositSession closes the session and awards the points of its bottles and the extra points
+	// of the award in one ledger entry, the award is computed inside the transaction from the session it read
+	CloseDepositSession(sessionId int64, boxId int64, now time.Time, award AwardFunc) (*DepositSession, error)
+	// ExpiredDepositSessions returns the open and claimed sessions that expired before now
+	ExpiredDepositSessions(now time.Time) ([]*DepositSession, error)
 }
+
+// AwardFunc returns what the point rules add to a deposit of basePoints by the user at the box. The storage calls it
+// inside the transaction that pays the deposit, before writing, with a usage reader bound to that transaction
+// and the box as it was before the deposit.
+type AwardFunc func(usage rule.UsageReader, box *RecycleBox, userId int64, basePoints int64) (*rule.Award, error)
@@ -0,0 +1,13 @@
+package referral
+
+// SummaryDTO is the response of GET /me/referrals, the counts are by referral status
+type SummaryDTO struct {
+	InviteCode    string `json:"invite_code"`
+	Pending       int64  `json:"pending"`
+	Rewarded      int64  `json:"rewarded"`
+	Capped        int64  `json:"capped"`
+	Blocked       int64  `json:"blocked"`
+	MaxRewarded   int64  `json:"max_rewarded"`
+	ReferrerBonus int64  `json:"referrer_bonus"`
+	RefereeBonus  int64  `json:"referee_bonus"`
+}
@@ -0,0 +1,34 @@
+package referral
+
+import "time"
+
+const (
+	StatusPending  = "pending"
+	StatusRewarded = "rewarded"
+	StatusCapped   = "capped"
+	StatusBlocked  = "blocked"
+)
+
+// Referral links a new user to the user whose invite code they registered with.
+// It is settled by the first deposit of the referee.
+type Referral struct {
+	RefereeID  int64      `json:"referee_id"`
+	ReferrerID int64      `json:"referrer_id"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SettledAt  *time.Time `json:"settled_at"`
+}
+
+// Referrer is the owner of an invite code
+type Referrer struct {
+	ID      int64
+	Email   string
+	Blocked bool
+}
+
+// Bonus is what a settled referral credits to each side
+type Bonus struct {
+	Referrer   int64
+	Referee    int64
+	MaxRewards int64
+}
@@ -0,0 +1,106 @@
+package referral
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	ReferrerBonus = 200
+	RefereeBonus  = 100
+	// MaxRewardedReferrals is how many referrals of a user are rewarded, later ones are settled as capped
+	MaxRewardedReferrals = 20
+)
+
+type ServiceReferral interface {
+	// Validate checks the code a new user registers with and returns the referrer
+	Validate(ctx context.Context, code string, email string) (int64, error)
+	Refer(ctx context.Context, referrerID int64, refereeID int64) error
+	Summary(ctx context.Context, userID int64) (*SummaryDTO, error)
+	OnDeposit(ctx context.Context, userID int64) (*Referral, error)
+}
+
+type serviceReferral struct {
+	storage ReferralStorage
+}
+
+func NewReferralService(storage ReferralStorage) ServiceReferral {
+	return &serviceReferral{
+		storage: storage,
+	}
+}
+
+// Validate rejects unknown codes, codes of blocked users and codes of the same mailbox as email
+func (s *serviceReferral) Validate(ctx context.Context, code string, email string) (int64, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return 0, customError.InvalidReferralCodeError
+	}
+	r, err := s.storage.GetReferrerByCode(code)
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return 0, customError.InvalidReferralCodeError
+		}
+		return 0, err
+	}
+	if r.Blocked {
+		return 0, customError.InvalidReferralCodeError
+	}
+	if mailbox(r.Email) == mailbox(email) {
+		return 0, customError.SelfReferralError
+	}
+	return r.ID, nil
+}
+
+func (s *serviceReferral) Refer(ctx context.Context, referrerID int64, refereeID int64) error {
+	if referrerID == refereeID {
+		return customError.SelfReferralError
+	}
+	return s.storage.CreateReferral(&Referral{
+		RefereeID:  refereeID,
+		ReferrerID: referrerID,
+		Status:     StatusPending,
+		CreatedAt:  time.Now().UTC(),
+	})
+}
+
+// Summary returns the invite code of the user and how their referrals were settled
+func (s *serviceReferral) Summary(ctx context.Context, userID int64) (*SummaryDTO, error) {
+	code, err := s.storage.GetInviteCode(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.storage.CountReferrals(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &SummaryDTO{
+		InviteCode:    code,
+		Pending:       counts[StatusPending],
+		Rewarded:      counts[StatusRewarded],
+		Capped:        counts[StatusCapped],
+		Blocked:       counts[StatusBlocked],
+		MaxRewarded:   MaxRewardedReferrals,
+		ReferrerBonus: ReferrerBonus,
+		RefereeBonus:  RefereeBonus,
+	}, nil
+}
+
+// OnDeposit settles the referral of the user on their first deposit, later calls do nothing
+func (s *serviceReferral) OnDeposit(ctx context.Context, userID int64) (*Referral, error) {
+	bonus := &Bonus{Referrer: ReferrerBonus, Referee: RefereeBonus, MaxRewards: MaxRewardedReferrals}
+	return s.storage.SettleReferral(userID, bonus, time.Now().UTC())
+}
+
+// mailbox drops the case and the +tag of an address, so a user cannot refer their own aliases
+func mailbox(email string) string {
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return local
+	}
+	local, _, _ = strings.Cut(local, "+")
+	return local + "@" + domain
+}
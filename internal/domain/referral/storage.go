@@ -0,0 +1,15 @@
+package referral
+
+import "time"
+
+type ReferralStorage interface {
+	GetInviteCode(userID int64) (string, error)
+	GetReferrerByCode(code string) (*Referrer, error)
+	CreateReferral(r *Referral) error
+	// CountReferrals counts the referrals of the referrer by status
+	CountReferrals(referrerID int64) (map[string]int64, error)
+	// SettleReferral settles the pending referral of the referee in one transaction and credits
+	// the bonus when both accounts are active and the referrer is below bonus.MaxRewards.
+	// It returns nil when the referee has no pending referral.
+	SettleReferral(refereeID int64, bonus *Bonus, now time.Time) (*Referral, error)
+}
@@ -0,0 +1,28 @@
+package reward
+
+type CreateRewardDTO struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Kind        string `json:"kind"`
+	PartnerID   *int64 `json:"partner_id"`
+	Cost        int64  `json:"cost"`
+	Stock       *int64 `json:"stock"`
+}
+
+// UpdateRewardDTO changes only the fields that are set, Stock replaces the remaining stock
+// and Unlimited makes the stock unlimited again, the two cannot be set together
+type UpdateRewardDTO struct {
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+	PartnerID   *int64  `json:"partner_id"`
+	Cost        *int64  `json:"cost"`
+	Stock       *int64  `json:"stock"`
+	Unlimited   bool    `json:"unlimited"`
+	Active      *bool   `json:"active"`
+}
+
+// RedemptionDTO is returned to the partner that validates a code
+type RedemptionDTO struct {
+	*Redemption
+	Reward *Reward `json:"reward"`
+}
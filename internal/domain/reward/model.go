@@ -0,0 +1,39 @@
+package reward
+
+import "time"
+
+const (
+	KindVoucher  = "voucher"
+	KindDiscount = "discount"
+
+	RedemptionIssued   = "issued"
+	RedemptionConsumed = "consumed"
+)
+
+// Reward is an item of the catalog users pay for with points.
+// PartnerID is the account that consumes its codes, Stock nil is unlimited.
+type Reward struct {
+	ID          int64     `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Kind        string    `json:"kind"`
+	PartnerID   *int64    `json:"partner_id"`
+	Cost        int64     `json:"cost"`
+	Stock       *int64    `json:"stock"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Redemption is a reward a user paid for, the code is shown to the partner
+type Redemption struct {
+	ID            int64      `json:"id"`
+	RewardID      int64      `json:"reward_id"`
+	UserID        int64      `json:"user_id"`
+	TransactionID *int64     `json:"transaction_id"`
+	Code          string     `json:"code"`
+	Cost          int64      `json:"cost"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	ConsumedAt    *time.Time `json:"consumed_at"`
+	ConsumedBy    *int64     `json:"consumed_by,omitempty"`
+}
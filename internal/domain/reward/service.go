@@ -0,0 +1,185 @@
+package reward
+
+import (
+	"auth-api/internal/domain/rbac"
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"strings"
+	"time"
+)
+
+const redemptionCodeLength = 12
+
+type ServiceReward interface {
+	ListRewards(ctx context.Context, all bool) ([]*Reward, error)
+	CreateReward(ctx context.Context, dto *CreateRewardDTO) (*Reward, error)
+	UpdateReward(ctx context.Context, id int64, dto *UpdateRewardDTO) (*Reward, error)
+	Redeem(ctx context.Context, rewardID int64, userID int64) (*Redemption, error)
+	ListRedemptions(ctx context.Context, userID int64) ([]*Redemption, error)
+	ValidateRedemption(ctx context.Context, code string, partnerID int64, role string) (*RedemptionDTO, error)
+	ConsumeRedemption(ctx context.Context, code string, partnerID int64, role string) (*RedemptionDTO, error)
+}
+
+type serviceReward struct {
+	storage RewardStorage
+}
+
+func NewRewardService(storage RewardStorage) ServiceReward {
+	return &serviceReward{
+		storage: storage,
+	}
+}
+
+// ListRewards returns the catalog, inactive rewards only when all is set
+func (s *serviceReward) ListRewards(ctx context.Context, all bool) ([]*Reward, error) {
+	rewards, err := s.storage.ListRewards(!all)
+	if err != nil {
+		return nil, err
+	}
+	if rewards == nil {
+		rewards = []*Reward{}
+	}
+	return rewards, nil
+}
+
+func (s *serviceReward) CreateReward(ctx context.Context, dto *CreateRewardDTO) (*Reward, error) {
+	r := &Reward{
+		Title:       strings.TrimSpace(dto.Title),
+		Description: strings.TrimSpace(dto.Description),
+		Kind:        dto.Kind,
+		PartnerID:   dto.PartnerID,
+		Cost:        dto.Cost,
+		Stock:       dto.Stock,
+		Active:      true,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := validReward(r); err != nil {
+		return nil, err
+	}
+	if err := s.storage.CreateReward(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// UpdateReward changes a reward, the codes already issued keep the cost they were paid with
+func (s *serviceReward) UpdateReward(ctx context.Context, id int64, dto *UpdateRewardDTO) (*Reward, error) {
+	r, err := s.storage.GetReward(id)
+	if err != nil {
+		return nil, err
+	}
+	if title := strings.TrimSpace(dto.Title); title != "" {
+		r.Title = title
+	}
+	if dto.Description != nil {
+		r.Description = strings.TrimSpace(*dto.Description)
+	}
+	if dto.PartnerID != nil {
+		r.PartnerID = dto.PartnerID
+	}
+	if dto.Cost != nil {
+		r.Cost = *dto.Cost
+	}
+	if dto.Stock != nil && dto.Unlimited {
+		return nil, customError.InvalidRewardError
+	}
+	if dto.Stock != nil || dto.Unlimited {
+		r.Stock = dto.Stock
+	}
+	if dto.Active != nil {
+		r.Active = *dto.Active
+	}
+	if err := validReward(r); err != nil {
+		return nil, err
+	}
+	if err := s.storage.UpdateReward(r); err != nil {
+		return nil, err
+	}
+	// The stock is written apart from the other fields, so an edit does not undo a concurrent redemption
+	if dto.Stock != nil || dto.Unlimited {
+		if err := s.storage.SetRewardStock(id, dto.Stock); err != nil {
+			return nil, err
+		}
+	}
+	return s.storage.GetReward(id)
+}
+
+// Redeem pays for the reward with the points of the user and issues the code for the partner
+func (s *serviceReward) Redeem(ctx context.Context, rewardID int64, userID int64) (*Redemption, error) {
+	code, err := utils.RandomCode(redemptionCodeLength)
+	if err != nil {
+		return nil, err
+	}
+	return s.storage.Redeem(rewardID, userID, code, time.Now().UTC())
+}
+
+func (s *serviceReward) ListRedemptions(ctx context.Context, userID int64) ([]*Redemption, error) {
+	redemptions, err := s.storage.ListRedemptions(userID)
+	if err != nil {
+		return nil, err
+	}
+	if redemptions == nil {
+		redemptions = []*Redemption{}
+	}
+	return redemptions, nil
+}
+
+// ValidateRedemption shows the partner what the code is for and whether it is still valid
+func (s *serviceReward) ValidateRedemption(ctx context.Context, code string, partnerID int64, role string) (*RedemptionDTO, error) {
+	rd, r, err := s.partnerRedemption(code, partnerID, role)
+	if err != nil {
+		return nil, err
+	}
+	return &RedemptionDTO{Redemption: rd, Reward: r}, nil
+}
+
+// ConsumeRedemption marks the code used, the partner hands out the reward after that
+func (s *serviceReward) ConsumeRedemption(ctx context.Context, code string, partnerID int64, role string) (*RedemptionDTO, error) {
+	rd, r, err := s.partnerRedemption(code, partnerID, role)
+	if err != nil {
+		return nil, err
+	}
+	if rd.Status == RedemptionConsumed {
+		return nil, customError.RedemptionConsumedError
+	}
+	if rd, err = s.storage.ConsumeRedemption(rd.ID, partnerID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return &RedemptionDTO{Redemption: rd, Reward: r}, nil
+}
+
+// partnerRedemption finds the redemption of the code, codes of a reward bound to a partner
+// are visible only to that partner. Only the partner role is bound to rewards, the other roles
+// with reward:consume, such as admins, see every code.
+func (s *serviceReward) partnerRedemption(code string, partnerID int64, role string) (*Redemption, *Reward, error) {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if code == "" {
+		return nil, nil, customError.NotFoundError
+	}
+	rd, err := s.storage.GetRedemptionByCode(code)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := s.storage.GetReward(rd.RewardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == rbac.RolePartner && r.PartnerID != nil && *r.PartnerID != partnerID {
+		return nil, nil, customError.NotFoundError
+	}
+	return rd, r, nil
+}
+
+func validReward(r *Reward) error {
+	if r.Title == "" || r.Cost <= 0 {
+		return customError.InvalidRewardError
+	}
+	if r.Kind != KindVoucher && r.Kind != KindDiscount {
+		return customError.InvalidRewardError
+	}
+	if r.Stock != nil && *r.Stock < 0 {
+		return customError.InvalidRewardError
+	}
+	return nil
+}
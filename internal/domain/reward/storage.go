@@ -0,0 +1,20 @@
+package reward
+
+import "time"
+
+type RewardStorage interface {
+	ListRewards(activeOnly bool) ([]*Reward, error)
+	GetReward(id int64) (*Reward, error)
+	CreateReward(r *Reward) error
+	// UpdateReward writes every field but the stock
+	UpdateReward(r *Reward) error
+	// SetRewardStock replaces the remaining stock, nil is unlimited
+	SetRewardStock(id int64, stock *int64) error
+	// Redeem reserves one item of the stock, debits the cost from the user and records the redemption,
+	// all or nothing
+	Redeem(rewardID int64, userID int64, code string, now time.Time) (*Redemption, error)
+	ListRedemptions(userID int64) ([]*Redemption, error)
+	GetRedemptionByCode(code string) (*Redemption, error)
+	// ConsumeRedemption marks an issued redemption consumed, a code can be consumed only once
+	ConsumeRedemption(id int64, partnerID int64, now time.Time) (*Redemption, error)
+}
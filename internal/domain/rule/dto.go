@@ -0,0 +1,23 @@
+package rule
+
+import "time"
+
+// RuleDTO is the body of POST /admin/rules and PUT /admin/rules/{id}, PUT replaces the whole rule
+type RuleDTO struct {
+	Name              string     `json:"name"`
+	Description       string     `json:"description"`
+	Multiplier        float64    `json:"multiplier"`
+	Bonus             int64      `json:"bonus"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	Weekdays          []int      `json:"weekdays"`
+	BoxIDs            []int64    `json:"box_ids"`
+	Zone              *Zone      `json:"zone"`
+	FirstDepositOfDay bool       `json:"first_deposit_of_day"`
+	MaxFillPercent    *int64     `json:"max_fill_percent"`
+	UserDailyCap      int64      `json:"user_daily_cap"`
+	UserTotalCap      int64      `json:"user_total_cap"`
+	Priority          int64      `json:"priority"`
+	Exclusive         bool       `json:"exclusive"`
+	Active            *bool      `json:"active"`
+}
@@ -0,0 +1,61 @@
+package rule
+
+import (
+	"auth-api/internal/domain/points"
+	"time"
+)
+
+// Rule adds extra points to the deposits that match all of its conditions.
+// The extra points are Multiplier-1 times the base points of the materials plus Bonus.
+type Rule struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Multiplier  float64 `json:"multiplier"`
+	Bonus       int64   `json:"bonus"`
+	// StartsAt and EndsAt bound the campaign, nil is open-ended
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	// Weekdays limits the rule to days of the week in UTC (0 is Sunday), empty matches every day
+	Weekdays []int `json:"weekdays"`
+	// BoxIDs and Zone target boxes, a deposit must match both when both are set
+	BoxIDs []int64 `json:"box_ids"`
+	Zone   *Zone   `json:"zone"`
+	// FirstDepositOfDay matches only the first deposit of the user in a UTC day
+	FirstDepositOfDay bool `json:"first_deposit_of_day"`
+	// MaxFillPercent matches boxes filled at most this much, it rewards under-used boxes
+	MaxFillPercent *int64 `json:"max_fill_percent"`
+	// UserDailyCap and UserTotalCap limit the extra points a user gets from the rule, 0 is unlimited
+	UserDailyCap int64 `json:"user_daily_cap"`
+	UserTotalCap int64 `json:"user_total_cap"`
+	// Rules are applied by descending priority, an exclusive rule does not stack with others
+	Priority  int64     `json:"priority"`
+	Exclusive bool      `json:"exclusive"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Zone is a circle around a point, Radius is in meters
+type Zone struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Radius    float64 `json:"radius"`
+}
+
+// Deposit is what the rules are evaluated against, the box state is from before the deposit
+type Deposit struct {
+	UserID     int64
+	BoxID      int64
+	Latitude   *float64
+	Longitude  *float64
+	Count      int64
+	Capacity   int64
+	BasePoints int64
+	At         time.Time
+}
+
+// Award is the result of the evaluation, Extra is added to the base points
+type Award struct {
+	Extra int64
+	Rules []*points.TransactionRule
+}
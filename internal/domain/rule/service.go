@@ -0,0 +1,253 @@
+package rule
+
+import (
+	"auth-api/internal/domain/points"
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	MaxMultiplier = 10.0
+	MaxZoneRadius = 50000.0 // meters
+)
+
+type ServiceRule interface {
+	ListRules(ctx context.Context) ([]*Rule, error)
+	GetRule(ctx context.Context, id int64) (*Rule, error)
+	CreateRule(ctx context.Context, dto *RuleDTO) (*Rule, error)
+	UpdateRule(ctx context.Context, id int64, dto *RuleDTO) (*Rule, error)
+	Evaluate(ctx context.Context, d *Deposit, usage UsageReader) (*Award, error)
+}
+
+type serviceRule struct {
+	storage RuleStorage
+}
+
+func NewRuleService(storage RuleStorage) ServiceRule {
+	return &serviceRule{
+		storage: storage,
+	}
+}
+
+func (s *serviceRule) ListRules(ctx context.Context) ([]*Rule, error) {
+	rules, err := s.storage.ListRules()
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []*Rule{}
+	}
+	return rules, nil
+}
+
+func (s *serviceRule) GetRule(ctx context.Context, id int64) (*Rule, error) {
+	return s.storage.GetRule(id)
+}
+
+// CreateRule adds a rule, it applies to the next deposits
+func (s *serviceRule) CreateRule(ctx context.Context, dto *RuleDTO) (*Rule, error) {
+	r := &Rule{Active: true, CreatedAt: time.Now().UTC()}
+	applyRuleDTO(r, dto)
+	if err := validRule(r); err != nil {
+		return nil, err
+	}
+	if err := s.storage.CreateRule(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// UpdateRule replaces the rule, the points already awarded by it are kept
+func (s *serviceRule) UpdateRule(ctx context.Context, id int64, dto *RuleDTO) (*Rule, error) {
+	r, err := s.storage.GetRule(id)
+	if err != nil {
+		return nil, err
+	}
+	applyRuleDTO(r, dto)
+	if err := validRule(r); err != nil {
+		return nil, err
+	}
+	if err := s.storage.UpdateRule(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Evaluate returns the extra points the active rules add to the deposit and what every rule contributed.
+// Rules are applied by descending priority and stack; an exclusive rule applies only when no rule
+// applied before it and ends the evaluation. A capped rule contributes what is left of its cap.
+// usage must read through the transaction that writes the deposit.
+func (s *serviceRule) Evaluate(ctx context.Context, d *Deposit, usage UsageReader) (*Award, error) {
+	award := &Award{Rules: []*points.TransactionRule{}}
+	if d.BasePoints <= 0 {
+		return award, nil
+	}
+	rules, err := s.storage.ListActiveRules(d.At)
+	if err != nil {
+		return nil, err
+	}
+	day := d.At.UTC().Truncate(24 * time.Hour)
+	var depositsToday *int64
+	for _, r := range rules {
+		if r.Exclusive && len(award.Rules) > 0 {
+			continue
+		}
+		if !r.matches(d) {
+			continue
+		}
+		if r.FirstDepositOfDay {
+			if depositsToday == nil {
+				n, err := usage.CountDeposits(d.UserID, day)
+				if err != nil {
+					return nil, err
+				}
+				depositsToday = &n
+			}
+			if *depositsToday > 0 {
+				continue
+			}
+		}
+		extra := int64(math.Round(float64(d.BasePoints)*(r.Multiplier-1))) + r.Bonus
+		if extra, err = capExtra(usage, r, d.UserID, day, extra); err != nil {
+			return nil, err
+		}
+		if extra <= 0 {
+			continue
+		}
+		award.Extra += extra
+		award.Rules = append(award.Rules, &points.TransactionRule{RuleID: r.ID, Points: extra})
+		if r.Exclusive {
+			break
+		}
+	}
+	return award, nil
+}
+
+// capExtra lowers the extra points to what is left of the caps of the rule for the user
+func capExtra(usage UsageReader, r *Rule, userID int64, day time.Time, extra int64) (int64, error) {
+	if r.UserDailyCap > 0 {
+		used, err := usage.RuleUsage(r.ID, userID, day)
+		if err != nil {
+			return 0, err
+		}
+		extra = min(extra, r.UserDailyCap-used)
+	}
+	if r.UserTotalCap > 0 {
+		used, err := usage.RuleUsage(r.ID, userID, time.Time{})
+		if err != nil {
+			return 0, err
+		}
+		extra = min(extra, r.UserTotalCap-used)
+	}
+	return extra, nil
+}
+
+// matches checks the conditions that depend only on the deposit
+func (r *Rule) matches(d *Deposit) bool {
+	at := d.At.UTC()
+	if r.StartsAt != nil && at.Before(*r.StartsAt) || r.EndsAt != nil && !at.Before(*r.EndsAt) {
+		return false
+	}
+	if len(r.Weekdays) > 0 && !contains(r.Weekdays, int(at.Weekday())) {
+		return false
+	}
+	if len(r.BoxIDs) > 0 && !contains(r.BoxIDs, d.BoxID) {
+		return false
+	}
+	if r.Zone != nil {
+		if d.Latitude == nil || d.Longitude == nil ||
+			utils.Distance(r.Zone.Latitude, r.Zone.Longitude, *d.Latitude, *d.Longitude) > r.Zone.Radius {
+			return false
+		}
+	}
+	if r.MaxFillPercent != nil && (d.Capacity <= 0 || d.Count*100 > *r.MaxFillPercent*d.Capacity) {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](list []T, v T) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func applyRuleDTO(r *Rule, dto *RuleDTO) {
+	r.Name = strings.TrimSpace(dto.Name)
+	r.Description = strings.TrimSpace(dto.Description)
+	r.Multiplier = dto.Multiplier
+	if r.Multiplier == 0 {
+		r.Multiplier = 1
+	}
+	r.Bonus = dto.Bonus
+	r.StartsAt, r.EndsAt = utcTime(dto.StartsAt), utcTime(dto.EndsAt)
+	r.Weekdays = dto.Weekdays
+	if r.Weekdays == nil {
+		r.Weekdays = []int{}
+	}
+	r.BoxIDs = dto.BoxIDs
+	if r.BoxIDs == nil {
+		r.BoxIDs = []int64{}
+	}
+	r.Zone = dto.Zone
+	r.FirstDepositOfDay = dto.FirstDepositOfDay
+	r.MaxFillPercent = dto.MaxFillPercent
+	r.UserDailyCap = dto.UserDailyCap
+	r.UserTotalCap = dto.UserTotalCap
+	r.Priority = dto.Priority
+	r.Exclusive = dto.Exclusive
+	if dto.Active != nil {
+		r.Active = *dto.Active
+	}
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+func validRule(r *Rule) error {
+	if r.Name == "" || r.Multiplier < 1 || r.Multiplier > MaxMultiplier || r.Bonus < 0 {
+		return customError.InvalidRuleError
+	}
+	// A rule must add something
+	if r.Multiplier == 1 && r.Bonus == 0 {
+		return customError.InvalidRuleError
+	}
+	if r.StartsAt != nil && r.EndsAt != nil && !r.EndsAt.After(*r.StartsAt) {
+		return customError.InvalidRuleError
+	}
+	for _, d := range r.Weekdays {
+		if d < 0 || d > 6 {
+			return customError.InvalidRuleError
+		}
+	}
+	for _, id := range r.BoxIDs {
+		if id <= 0 {
+			return customError.InvalidRuleError
+		}
+	}
+	if z := r.Zone; z != nil {
+		if z.Latitude < -90 || z.Latitude > 90 || z.Longitude < -180 || z.Longitude > 180 ||
+			z.Radius <= 0 || z.Radius > MaxZoneRadius {
+			return customError.InvalidRuleError
+		}
+	}
+	if p := r.MaxFillPercent; p != nil && (*p < 0 || *p > 100) {
+		return customError.InvalidRuleError
+	}
+	if r.UserDailyCap < 0 || r.UserTotalCap < 0 {
+		return customError.InvalidRuleError
+	}
+	return nil
+}
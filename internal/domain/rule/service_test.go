@@ -0,0 +1,190 @@
+package rule
+
+import (
+	"auth-api/internal/domain/points"
+	customError "auth-api/internal/error"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// memoryStorage returns its rules as the active ones, already ordered by descending priority like the SQLite storage
+type memoryStorage struct {
+	rules []*Rule
+}
+
+func (s *memoryStorage) ListRules() ([]*Rule, error)                   { return s.rules, nil }
+func (s *memoryStorage) ListActiveRules(at time.Time) ([]*Rule, error) { return s.rules, nil }
+func (s *memoryStorage) CreateRule(r *Rule) error                      { return nil }
+func (s *memoryStorage) UpdateRule(r *Rule) error                      { return nil }
+
+func (s *memoryStorage) GetRule(id int64) (*Rule, error) {
+	for _, r := range s.rules {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, customError.NotFoundError
+}
+
+// memoryUsage answers the usage reads from fixed counts, the daily usage is keyed by rule
+type memoryUsage struct {
+	depositsToday int64
+	dailyUsage    map[int64]int64
+	totalUsage    map[int64]int64
+}
+
+func (u *memoryUsage) CountDeposits(userID int64, since time.Time) (int64, error) {
+	return u.depositsToday, nil
+}
+
+func (u *memoryUsage) RuleUsage(ruleID int64, userID int64, since time.Time) (int64, error) {
+	if since.IsZero() {
+		return u.totalUsage[ruleID], nil
+	}
+	return u.dailyUsage[ruleID], nil
+}
+
+func TestEvaluate(t *testing.T) {
+	// 2024-06-05 is a Wednesday
+	at := time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC)
+	fill := int64(50)
+	tests := []struct {
+		name  string
+		rules []*Rule
+		usage *memoryUsage
+		want  []*points.TransactionRule
+	}{
+		{
+			name:  "no rule",
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{},
+		},
+		{
+			name: "multiplier and bonus stack",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 2},
+				{ID: 2, Multiplier: 1, Bonus: 3},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 1, Points: 10}, {RuleID: 2, Points: 3}},
+		},
+		{
+			name: "exclusive rule first ends the evaluation",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 3, Exclusive: true},
+				{ID: 2, Multiplier: 1, Bonus: 3},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 1, Points: 20}},
+		},
+		{
+			name: "exclusive rule after an applied one is skipped",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 1, Bonus: 3},
+				{ID: 2, Multiplier: 3, Exclusive: true},
+				{ID: 3, Multiplier: 1, Bonus: 1},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 1, Points: 3}, {RuleID: 3, Points: 1}},
+		},
+		{
+			name: "exclusive rule that does not match lets the others apply",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 3, Exclusive: true, BoxIDs: []int64{99}},
+				{ID: 2, Multiplier: 1, Bonus: 3},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 2, Points: 3}},
+		},
+		{
+			name: "daily cap leaves what is left",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 2, UserDailyCap: 15},
+			},
+			usage: &memoryUsage{dailyUsage: map[int64]int64{1: 12}},
+			want:  []*points.TransactionRule{{RuleID: 1, Points: 3}},
+		},
+		{
+			name: "total cap reached skips the rule",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 2, UserTotalCap: 100},
+				{ID: 2, Multiplier: 1, Bonus: 3},
+			},
+			usage: &memoryUsage{totalUsage: map[int64]int64{1: 100}},
+			want:  []*points.TransactionRule{{RuleID: 2, Points: 3}},
+		},
+		{
+			name: "capped exclusive rule with nothing left does not block the others",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 3, Exclusive: true, UserDailyCap: 20},
+				{ID: 2, Multiplier: 1, Bonus: 3},
+			},
+			usage: &memoryUsage{dailyUsage: map[int64]int64{1: 20}},
+			want:  []*points.TransactionRule{{RuleID: 2, Points: 3}},
+		},
+		{
+			name: "first deposit of the day",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 1, Bonus: 5, FirstDepositOfDay: true},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 1, Points: 5}},
+		},
+		{
+			name: "not the first deposit of the day",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 1, Bonus: 5, FirstDepositOfDay: true},
+			},
+			usage: &memoryUsage{depositsToday: 1},
+			want:  []*points.TransactionRule{},
+		},
+		{
+			name: "weekday and fill conditions",
+			rules: []*Rule{
+				{ID: 1, Multiplier: 1, Bonus: 5, Weekdays: []int{0, 6}},
+				{ID: 2, Multiplier: 1, Bonus: 4, Weekdays: []int{3}, MaxFillPercent: &fill},
+			},
+			usage: &memoryUsage{},
+			want:  []*points.TransactionRule{{RuleID: 2, Points: 4}},
+		},
+	}
+	for _, tt := range tests {
+		s := NewRuleService(&memoryStorage{rules: tt.rules})
+		d := &Deposit{UserID: 7, BoxID: 1, Count: 40, Capacity: 100, BasePoints: 10, At: at}
+		award, err := s.Evaluate(context.Background(), d, tt.usage)
+		if err != nil {
+			t.Fatalf("%s: Evaluate: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(award.Rules, tt.want) {
+			t.Errorf("%s: rules %v, want %v", tt.name, describe(award.Rules), describe(tt.want))
+		}
+		var extra int64
+		for _, r := range tt.want {
+			extra += r.Points
+		}
+		if award.Extra != extra {
+			t.Errorf("%s: extra %d, want %d", tt.name, award.Extra, extra)
+		}
+	}
+}
+
+func TestEvaluateWithoutBasePoints(t *testing.T) {
+	s := NewRuleService(&memoryStorage{rules: []*Rule{{ID: 1, Multiplier: 1, Bonus: 5}}})
+	award, err := s.Evaluate(context.Background(), &Deposit{At: time.Now()}, &memoryUsage{})
+	if err != nil {
+		t.Fatalf("Evaluate: %v", err)
+	}
+	if award.Extra != 0 || len(award.Rules) != 0 {
+		t.Errorf("a deposit without base points got %d extra points", award.Extra)
+	}
+}
+
+func describe(rules []*points.TransactionRule) []points.TransactionRule {
+	list := []points.TransactionRule{}
+	for _, r := range rules {
+		list = append(list, *r)
+	}
+	return list
+}
@@ -0,0 +1,21 @@
+package rule
+
+import "time"
+
+type RuleStorage interface {
+	ListRules() ([]*Rule, error)
+	// ListActiveRules returns the active rules whose campaign includes at, by descending priority
+	ListActiveRules(at time.Time) ([]*Rule, error)
+	GetRule(id int64) (*Rule, error)
+	CreateRule(r *Rule) error
+	UpdateRule(r *Rule) error
+}
+
+// UsageReader reads the ledger of a user for the conditions and caps of the rules. It is provided by
+// the transaction that pays the deposit, so concurrent deposits cannot both pass a cap or be the first of the day.
+type UsageReader interface {
+	// CountDeposits counts the deposit ledger entries of the user since the time
+	CountDeposits(userID int64, since time.Time) (int64, error)
+	// RuleUsage sums the points the rule contributed to the user since the time
+	RuleUsage(ruleID int64, userID int64, since time.Time) (int64, error)
+}
@@ -0,0 +1,17 @@
+package session
+
+import "time"
+
+// Session is a single refresh token issued to a user. Every refresh rotates the
+// token into a new Session that shares the same FamilyID, so the whole chain
+// can be revoked at once when reuse of an old token is detected.
+type Session struct {
+	ID        int64     `json:"id"`
+	FamilyID  string    `json:"family_id"`
+	UserID    int64     `json:"user_id"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Used      bool      `json:"used"`
+	Revoked   bool      `json:"revoked"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,124 @@
+package session
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	RefreshTokenTTL = time.Hour * 24 * 30
+)
+
+type ServiceSession interface {
+	Start(ctx context.Context, userID int64) (*Session, string, error)
+	Rotate(ctx context.Context, refreshToken string) (*Session, string, error)
+	Revoke(ctx context.Context, refreshToken string) error
+	RevokeUser(ctx context.Context, userID int64) error
+	IsRevoked(ctx context.Context, familyID string) bool
+}
+
+type serviceSession struct {
+	storage SessionStorage
+}
+
+func NewSessionService(storage SessionStorage) ServiceSession {
+	return &serviceSession{
+		storage: storage,
+	}
+}
+
+// Start opens a new session family for the user and returns the raw refresh token
+func (s *serviceSession) Start(ctx context.Context, userID int64) (*Session, string, error) {
+	familyID, err := utils.RandomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	return s.issue(userID, familyID)
+}
+
+// Rotate exchanges a refresh token for a new one in the same family.
+// Presenting a token that was already rotated revokes the whole family.
+func (s *serviceSession) Rotate(ctx context.Context, refreshToken string) (*Session, string, error) {
+	sess, err := s.storage.GetSessionByTokenHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return nil, "", customError.InvalidRefreshTokenError
+		}
+		return nil, "", err
+	}
+	if sess.Revoked {
+		return nil, "", customError.InvalidRefreshTokenError
+	}
+	if sess.Used {
+		log.Printf("refresh token reuse detected, revoking session family %s", sess.FamilyID)
+		if err := s.storage.RevokeFamily(sess.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", customError.RefreshTokenReuseError
+	}
+	if time.Now().UTC().After(sess.ExpiresAt) {
+		return nil, "", customError.InvalidRefreshTokenError
+	}
+	ok, err := s.storage.MarkSessionUsed(sess.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		// Another request rotated this token first, treat it as reuse
+		if err := s.storage.RevokeFamily(sess.FamilyID); err != nil {
+			return nil, "", err
+		}
+		return nil, "", customError.RefreshTokenReuseError
+	}
+	return s.issue(sess.UserID, sess.FamilyID)
+}
+
+// Revoke ends the session family the refresh token belongs to
+func (s *serviceSession) Revoke(ctx context.Context, refreshToken string) error {
+	sess, err := s.storage.GetSessionByTokenHash(utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.InvalidRefreshTokenError
+		}
+		return err
+	}
+	return s.storage.RevokeFamily(sess.FamilyID)
+}
+
+// RevokeUser ends every session of the user
+func (s *serviceSession) RevokeUser(ctx context.Context, userID int64) error {
+	return s.storage.RevokeUserSessions(userID)
+}
+
+// IsRevoked reports whether access tokens of the session family must be refused
+func (s *serviceSession) IsRevoked(ctx context.Context, familyID string) bool {
+	active, err := s.storage.IsFamilyActive(familyID)
+	if err != nil {
+		log.Println(err.Error())
+		return true
+	}
+	return !active
+}
+
+func (s *serviceSession) issue(userID int64, familyID string) (*Session, string, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	sess := &Session{
+		FamilyID:  familyID,
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: now.Add(RefreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.storage.CreateSession(sess); err != nil {
+		return nil, "", err
+	}
+	return sess, token, nil
+}
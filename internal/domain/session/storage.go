@@ -0,0 +1,10 @@
+package session
+
+type SessionStorage interface {
+	CreateSession(s *Session) error
+	GetSessionByTokenHash(hash string) (*Session, error)
+	MarkSessionUsed(id int64) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeUserSessions(userID int64) error
+	IsFamilyActive(familyID string) (bool, error)
+}
@@ -0,0 +1,27 @@
+package throttle
+
+import "time"
+
+// Scopes of the counters, a key is counted separately in every scope
+const (
+	ScopeLoginIP      = "login-ip"
+	ScopeLoginAccount = "login-account"
+	ScopeRegisterIP   = "register-ip"
+)
+
+// Attempts is the counter of one key, LockedUntil is zero when the key is not locked
+type Attempts struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+	LockedUntil time.Time
+}
+
+// Policy locks a key for Lockout after MaxFailures failures within Window,
+// every further failure doubles the lockout up to MaxLockout
+type Policy struct {
+	MaxFailures int
+	Window      time.Duration
+	Lockout     time.Duration
+	MaxLockout  time.Duration
+}
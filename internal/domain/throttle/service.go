@@ -0,0 +1,108 @@
+package throttle
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// attemptsRetention is how long a counter is kept after its last failure
+	attemptsRetention = time.Hour * 24
+	purgeInterval     = time.Hour
+)
+
+// DefaultPolicies are the limits of /login, /login/2fa and /register. Registrations are counted
+// with Hit whether they succeed or not, so one address cannot create accounts in bulk.
+var DefaultPolicies = map[string]Policy{
+	ScopeLoginIP:      {MaxFailures: 20, Window: time.Hour, Lockout: time.Minute, MaxLockout: time.Hour},
+	ScopeLoginAccount: {MaxFailures: 5, Window: time.Hour, Lockout: time.Minute, MaxLockout: time.Minute * 30},
+	ScopeRegisterIP:   {MaxFailures: 10, Window: time.Hour, Lockout: time.Minute * 10, MaxLockout: time.Hour},
+}
+
+type ServiceThrottle interface {
+	// Allow returns TooManyAttemptsError and the time left when the key is locked
+	Allow(ctx context.Context, scope, key string) (time.Duration, error)
+	// Fail counts a failed attempt and locks the key once the policy is exceeded
+	Fail(ctx context.Context, scope, key string) error
+	// Hit counts an attempt whatever its outcome, for scopes that limit how often an action is done
+	Hit(ctx context.Context, scope, key string) error
+	Reset(ctx context.Context, scope, key string) error
+}
+
+type serviceThrottle struct {
+	storage  AttemptStorage
+	policies map[string]Policy
+	purges   *utils.Interval
+}
+
+func NewThrottleService(storage AttemptStorage, policies map[string]Policy) ServiceThrottle {
+	return &serviceThrottle{
+		storage:  storage,
+		policies: policies,
+		purges:   &utils.Interval{Every: purgeInterval},
+	}
+}
+
+func (s *serviceThrottle) Allow(ctx context.Context, scope, key string) (time.Duration, error) {
+	a, err := s.storage.GetAttempts(counterKey(scope, key))
+	if err != nil {
+		return 0, err
+	}
+	if left := time.Until(a.LockedUntil); left > 0 {
+		return left, customError.TooManyAttemptsError
+	}
+	return 0, nil
+}
+
+func (s *serviceThrottle) Fail(ctx context.Context, scope, key string) error {
+	return s.count(scope, key)
+}
+
+func (s *serviceThrottle) Hit(ctx context.Context, scope, key string) error {
+	return s.count(scope, key)
+}
+
+// count adds an attempt to the counter of the key and locks it once the policy is exceeded
+func (s *serviceThrottle) count(scope, key string) error {
+	p, ok := s.policies[scope]
+	if !ok {
+		return fmt.Errorf("no throttle policy for %q", scope)
+	}
+	now := time.Now().UTC()
+	s.purge(now)
+	a, err := s.storage.AddFailure(counterKey(scope, key), now, p.Window)
+	if err != nil {
+		return err
+	}
+	if a.Failures < p.MaxFailures {
+		return nil
+	}
+	lockout := p.MaxLockout
+	if extra := a.Failures - p.MaxFailures; extra < 32 && p.Lockout<<extra < p.MaxLockout {
+		lockout = p.Lockout << extra
+	}
+	log.Printf("%s %s locked for %s after %d attempts", scope, key, lockout, a.Failures)
+	return s.storage.LockUntil(counterKey(scope, key), now.Add(lockout))
+}
+
+func (s *serviceThrottle) Reset(ctx context.Context, scope, key string) error {
+	return s.storage.ResetAttempts(counterKey(scope, key))
+}
+
+// purge drops old counters at most once per purgeInterval, failures are only logged
+func (s *serviceThrottle) purge(now time.Time) {
+	if !s.purges.Due(now) {
+		return
+	}
+	if err := s.storage.DeleteStaleAttempts(now.Add(-attemptsRetention)); err != nil {
+		log.Printf("cannot purge attempts: %v", err)
+	}
+}
+
+func counterKey(scope, key string) string {
+	return scope + ":" + key
+}
@@ -0,0 +1,153 @@
+package throttle
+
+import (
+	customError "auth-api/internal/error"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryStorage keeps the counters in a map, like the SQLite storage it restarts a count older than the window
+type memoryStorage struct {
+	attempts map[string]*Attempts
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{attempts: make(map[string]*Attempts)}
+}
+
+func (s *memoryStorage) GetAttempts(key string) (*Attempts, error) {
+	a, ok := s.attempts[key]
+	if !ok {
+		return &Attempts{Key: key}, nil
+	}
+	return a, nil
+}
+
+func (s *memoryStorage) AddFailure(key string, now time.Time, window time.Duration) (*Attempts, error) {
+	a, ok := s.attempts[key]
+	if !ok || now.Sub(a.LastFailure) > window {
+		a = &Attempts{Key: key}
+		s.attempts[key] = a
+	}
+	a.Failures++
+	a.LastFailure = now
+	return a, nil
+}
+
+func (s *memoryStorage) LockUntil(key string, until time.Time) error {
+	s.attempts[key].LockedUntil = until
+	return nil
+}
+
+func (s *memoryStorage) ResetAttempts(key string) error {
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *memoryStorage) DeleteStaleAttempts(before time.Time) error {
+	for key, a := range s.attempts {
+		if a.LastFailure.Before(before) {
+			delete(s.attempts, key)
+		}
+	}
+	return nil
+}
+
+var testPolicy = Policy{MaxFailures: 3, Window: time.Hour, Lockout: time.Minute, MaxLockout: 5 * time.Minute}
+
+func TestCountLockoutBackoff(t *testing.T) {
+	// The lockout starts at the failure that reaches MaxFailures and doubles with every further one
+	tests := []struct {
+		failures int
+		lockout  time.Duration
+	}{
+		{1, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 5 * time.Minute},
+		{40, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		storage := newMemoryStorage()
+		s := NewThrottleService(storage, map[string]Policy{ScopeLoginAccount: testPolicy})
+		var before time.Time
+		for i := 0; i < tt.failures; i++ {
+			before = time.Now().UTC()
+			if err := s.Fail(context.Background(), ScopeLoginAccount, "alice"); err != nil {
+				t.Fatalf("%d failures: Fail: %v", tt.failures, err)
+			}
+		}
+		a, _ := storage.GetAttempts(counterKey(ScopeLoginAccount, "alice"))
+		if tt.lockout == 0 {
+			if !a.LockedUntil.IsZero() {
+				t.Errorf("%d failures: locked until %s, want not locked", tt.failures, a.LockedUntil)
+			}
+			continue
+		}
+		if got := a.LockedUntil.Sub(before); got < tt.lockout || got > tt.lockout+time.Second {
+			t.Errorf("%d failures: lockout %s, want %s", tt.failures, got, tt.lockout)
+		}
+	}
+}
+
+func TestAllowRefusesLockedKey(t *testing.T) {
+	s := NewThrottleService(newMemoryStorage(), map[string]Policy{ScopeLoginAccount: testPolicy})
+	ctx := context.Background()
+	for i := 0; i < testPolicy.MaxFailures; i++ {
+		if _, err := s.Allow(ctx, ScopeLoginAccount, "alice"); err != nil {
+			t.Fatalf("Allow after %d failures: %v", i, err)
+		}
+		if err := s.Fail(ctx, ScopeLoginAccount, "alice"); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	left, err := s.Allow(ctx, ScopeLoginAccount, "alice")
+	if !errors.Is(err, customError.TooManyAttemptsError) {
+		t.Fatalf("Allow of a locked key = %v, want TooManyAttemptsError", err)
+	}
+	if left <= 0 || left > testPolicy.Lockout {
+		t.Errorf("Allow of a locked key left %s, want up to %s", left, testPolicy.Lockout)
+	}
+	if _, err := s.Allow(ctx, ScopeLoginAccount, "bob"); err != nil {
+		t.Errorf("Allow of another key: %v", err)
+	}
+	if _, err := s.Allow(ctx, ScopeLoginIP, "alice"); err != nil {
+		t.Errorf("Allow of the key in another scope: %v", err)
+	}
+	if err := s.Reset(ctx, ScopeLoginAccount, "alice"); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+	if _, err := s.Allow(ctx, ScopeLoginAccount, "alice"); err != nil {
+		t.Errorf("Allow after Reset: %v", err)
+	}
+}
+
+func TestCountRestartsAfterWindow(t *testing.T) {
+	storage := newMemoryStorage()
+	s := NewThrottleService(storage, map[string]Policy{ScopeLoginAccount: testPolicy})
+	key := counterKey(ScopeLoginAccount, "alice")
+	for i := 0; i < testPolicy.MaxFailures-1; i++ {
+		if err := s.Fail(context.Background(), ScopeLoginAccount, "alice"); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	storage.attempts[key].LastFailure = time.Now().UTC().Add(-testPolicy.Window - time.Minute)
+	if err := s.Fail(context.Background(), ScopeLoginAccount, "alice"); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	a, _ := storage.GetAttempts(key)
+	if a.Failures != 1 || !a.LockedUntil.IsZero() {
+		t.Errorf("after the window: %d failures, locked until %s, want 1 failure and not locked", a.Failures, a.LockedUntil)
+	}
+}
+
+func TestCountUnknownScope(t *testing.T) {
+	s := NewThrottleService(newMemoryStorage(), map[string]Policy{})
+	if err := s.Hit(context.Background(), ScopeRegisterIP, "10.0.0.1"); err == nil {
+		t.Error("Hit of a scope without policy succeeded, want an error")
+	}
+}
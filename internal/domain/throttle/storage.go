@@ -0,0 +1,13 @@
+package throttle
+
+import "time"
+
+type AttemptStorage interface {
+	// GetAttempts returns an empty counter for unknown keys
+	GetAttempts(key string) (*Attempts, error)
+	// AddFailure counts a failure, the count restarts when the last failure is older than window
+	AddFailure(key string, now time.Time, window time.Duration) (*Attempts, error)
+	LockUntil(key string, until time.Time) error
+	ResetAttempts(key string) error
+	DeleteStaleAttempts(before time.Time) error
+}
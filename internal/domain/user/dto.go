@@ -1,25 +1,144 @@
 package user
 
+import "time"
+
+// CreateUserDTO is the body of POST /register and POST /login, ReferralCode is the invite code
+// of the user who referred the new one and is used only by registration
 type CreateUserDTO struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	ReferralCode string `json:"referral_code,omitempty"`
 }
+
+// UpdateUserDTO is the body of PUT /settings, ID is taken from the token and never from the body
 type UpdateUserDTO struct {
+	ID              int64  `json:"-"`
+	Email           string `json:"email"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	CurrentPassword string `json:"current_password"`
+	PhoneNumber     string `json:"phone_number"`
+	BirthDate       string `json:"birth_date"`
+}
+
+type UpdateUserResponseDTO struct {
+	ProfileDTO
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+type ConfirmTokenDTO struct {
+	Token string `json:"token"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type LoginResponseDTO struct {
+	Token            string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// LoginChallengeDTO is returned by /login instead of tokens when the user has two-factor authentication
+type LoginChallengeDTO struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginDTO completes a login challenge with either a TOTP code or a recovery code
+type TwoFactorLoginDTO struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recovery_code"`
+}
+
+type TwoFactorEnrollmentDTO struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorDTO struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type RefreshTokenDTO struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type AuthDTO struct {
+	ID             int64  `json:"user_id"`
+	HashedPassword string `json:"hashed_password"`
+	Role           string `json:"role"`
+	Blocked        bool   `json:"blocked"`
+	TwoFactor      bool   `json:"two_factor"`
+}
+
+// ListUsersDTO holds the filters of GET /admin/users
+type ListUsersDTO struct {
+	Query  string
+	Limit  int64
+	Offset int64
+}
+
+// ProfileDTO is the public view of a user, responses must use it instead of User
+type ProfileDTO struct {
 	ID          int64  `json:"user_id"`
 	Email       string `json:"email"`
 	Username    string `json:"username"`
-	Password    string `json:"password"`
 	PhoneNumber string `json:"phone_number"`
 	BirthDate   string `json:"birth_date"`
+	Role        string `json:"role"`
 	Points      int64  `json:"points"`
+	Verified    bool   `json:"verified"`
+	TwoFactor   bool   `json:"two_factor"`
 }
 
-type LoginResponseDTO struct {
-	Token string `json:"token"`
+// AdminUserDTO is a user as seen by administrators
+type AdminUserDTO struct {
+	ProfileDTO
+	Blocked bool `json:"blocked"`
 }
 
-type AuthDTO struct {
-	ID             int64  `json:"user_id"`
-	HashedPassword string `json:"hashed_password"`
-	Role           string `json:"role"`
+type UserListDTO struct {
+	Items  []*AdminUserDTO `json:"items"`
+	Total  int64           `json:"total"`
+	Limit  int64           `json:"limit"`
+	Offset int64           `json:"offset"`
+}
+
+func NewProfileDTO(u *User) *ProfileDTO {
+	return &ProfileDTO{
+		ID:          u.ID,
+		Email:       u.Email,
+		Username:    u.Username,
+		PhoneNumber: u.PhoneNumber,
+		BirthDate:   u.BirthDate,
+		Role:        u.Role,
+		Points:      u.Points,
+		Verified:    u.Verified,
+		TwoFactor:   u.TwoFactor,
+	}
+}
+
+func NewAdminUserDTO(u *User) *AdminUserDTO {
+	return &AdminUserDTO{
+		ProfileDTO: *NewProfileDTO(u),
+		Blocked:    u.Blocked,
+	}
 }
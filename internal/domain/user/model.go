@@ -1,12 +1,63 @@
 package user
 
+import "time"
+
 type User struct {
 	ID             int64  `json:"user_id"`
 	Email          string `json:"email"`
 	Username       string `json:"username"`
-	HashedPassword string `json:"password"`
+	HashedPassword string `json:"-"`
 	PhoneNumber    string `json:"phone_number"`
 	BirthDate      string `json:"birth_date"`
 	Points         int64  `json:"points"`
 	Role           string `json:"role"`
+	Blocked        bool   `json:"blocked"`
+	Verified       bool   `json:"verified"`
+	TwoFactor      bool   `json:"two_factor"`
+}
+
+// EmailChange is a pending change of the user's email waiting for confirmation
+type EmailChange struct {
+	ID        int64
+	UserID    int64
+	NewEmail  string
+	TokenHash string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// PasswordReset is a single-use token letting the user set a new password
+type PasswordReset struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// EmailVerification is a token proving the user owns the registered address
+type EmailVerification struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// TwoFactor is the TOTP state of a user, Secret is set on enrollment and trusted once Enabled
+type TwoFactor struct {
+	UserID   int64
+	Secret   string
+	Enabled  bool
+	LastStep int64
+}
+
+// LoginChallenge is the second step of a login for users with two-factor authentication
+type LoginChallenge struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	Attempts  int
+	Used      bool
 }
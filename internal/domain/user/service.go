@@ -1,33 +1,72 @@
 package user
 
 import (
+	"auth-api/internal/domain/referral"
+	"auth-api/internal/domain/session"
 	customError "auth-api/internal/error"
 	"auth-api/internal/midlleware"
+	"auth-api/internal/utils"
+	"auth-api/pkg/client/mail"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"golang.org/x/crypto/bcrypt"
+	"log"
+	netMail "net/mail"
 	"os"
+	"strings"
 	"time"
 )
 
 var secretKey = []byte(os.Getenv("SECRET_KEY"))
 
+const (
+	AccessTokenTTL       = time.Minute * 15
+	EmailChangeTTL       = time.Hour * 24
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = time.Hour * 48
+	mailTimeout          = time.Second * 30
+
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
 type ServiceUser interface {
 	CreateUser(ctx context.Context, dto *CreateUserDTO) error
-	UpdateUser(ctx context.Context, dto *UpdateUserDTO) (*User, error)
-	Login(ctx context.Context, dto *CreateUserDTO) (*LoginResponseDTO, error)
+	UpdateUser(ctx context.Context, dto *UpdateUserDTO) (*UpdateUserResponseDTO, error)
+	ConfirmEmailChange(ctx context.Context, dto *ConfirmTokenDTO) error
+	ForgotPassword(ctx context.Context, dto *ForgotPasswordDTO) error
+	ResetPassword(ctx context.Context, dto *ResetPasswordDTO) error
+	VerifyEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, id int64) error
+	Login(ctx context.Context, dto *CreateUserDTO) (*LoginResponseDTO, *LoginChallengeDTO, error)
+	LoginTwoFactor(ctx context.Context, dto *TwoFactorLoginDTO) (*LoginResponseDTO, error)
+	ChallengeAccount(ctx context.Context, challengeToken string) (string, error)
+	EnrollTwoFactor(ctx context.Context, id int64) (*TwoFactorEnrollmentDTO, error)
+	ConfirmTwoFactor(ctx context.Context, id int64, dto *TwoFactorCodeDTO) (*RecoveryCodesDTO, error)
+	DisableTwoFactor(ctx context.Context, id int64, dto *DisableTwoFactorDTO) error
+	Refresh(ctx context.Context, refreshToken string) (*LoginResponseDTO, error)
+	Logout(ctx context.Context, refreshToken string) error
 	GetUserById(ctx context.Context, id int64) (*User, error)
+	ListUsers(ctx context.Context, dto *ListUsersDTO) (*UserListDTO, error)
+	SetUserBlocked(ctx context.Context, id int64, blocked bool) error
 	//GetUserByEmail(ctx context.Context, email string) (*User, error)
 }
 
 type serviceUser struct {
-	storage StorageUser
+	storage   StorageUser
+	sessions  session.ServiceSession
+	mailer    mail.Sender
+	referrals referral.ServiceReferral
 }
 
-func NewUserService(storage StorageUser) ServiceUser {
+func NewUserService(storage StorageUser, sessions session.ServiceSession, mailer mail.Sender, referrals referral.ServiceReferral) ServiceUser {
 	return &serviceUser{
-		storage: storage,
+		storage:   storage,
+		sessions:  sessions,
+		mailer:    mailer,
+		referrals: referrals,
 	}
 }
 
@@ -35,6 +74,37 @@ func (s *serviceUser) GetUserById(ctx context.Context, id int64) (*User, error)
 	return s.storage.GetUserById(id)
 }
 
+// ListUsers returns a page of users, optionally searched by email or username
+func (s *serviceUser) ListUsers(ctx context.Context, dto *ListUsersDTO) (*UserListDTO, error) {
+	if dto.Limit == 0 {
+		dto.Limit = DefaultListLimit
+	}
+	if dto.Limit < 0 || dto.Limit > MaxListLimit || dto.Offset < 0 {
+		return nil, customError.InvalidListQueryError
+	}
+	dto.Query = strings.TrimSpace(dto.Query)
+	users, total, err := s.storage.ListUsers(dto)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*AdminUserDTO, 0, len(users))
+	for _, u := range users {
+		items = append(items, NewAdminUserDTO(u))
+	}
+	return &UserListDTO{Items: items, Total: total, Limit: dto.Limit, Offset: dto.Offset}, nil
+}
+
+// SetUserBlocked blocks or unblocks the account, blocking also ends all its sessions
+func (s *serviceUser) SetUserBlocked(ctx context.Context, id int64, blocked bool) error {
+	if err := s.storage.SetUserBlocked(id, blocked); err != nil {
+		return err
+	}
+	if blocked {
+		return s.sessions.RevokeUser(ctx, id)
+	}
+	return nil
+}
+
 func (s *serviceUser) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	return s.storage.GetUserByEmail(email)
 }
@@ -48,67 +118,300 @@ func (s *serviceUser) CreateUser(ctx context.Context, dto *CreateUserDTO) error
 	if err == nil {
 		return customError.BusyUpdateEmailError
 	}
+	// The code is checked before the account exists, so a typo does not leave an unreferred user
+	var referrerID int64
+	if dto.ReferralCode != "" {
+		if referrerID, err = s.referrals.Validate(ctx, dto.ReferralCode, newUser.Email); err != nil {
+			return err
+		}
+	}
 	p, err := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return customError.CreateUserBadInputError
 	}
-	u := &User{Email: dto.Email, HashedPassword: string(p)}
-	if s.storage.CreateUser(u) != nil {
+	u := &User{Email: newUser.Email, HashedPassword: string(p)}
+	if err := s.storage.CreateUser(u); err != nil {
+		return err
+	}
+	if referrerID != 0 {
+		if err := s.referrals.Refer(ctx, referrerID, u.ID); err != nil {
+			log.Printf("cannot record referral of user %d by %d: %v", u.ID, referrerID, err)
+		}
+	}
+	return s.sendVerification(u)
+}
+
+// sendVerification emails a token that verifies the user's address
+func (s *serviceUser) sendVerification(u *User) error {
+	token, err := utils.RandomToken(32)
+	if err != nil {
 		return err
 	}
+	ev := &EmailVerification{
+		UserID:    u.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(EmailVerificationTTL),
+	}
+	if err := s.storage.CreateEmailVerification(ev); err != nil {
+		return err
+	}
+	s.sendMailAsync(&mail.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("Verify your email address with GET /verify?token=%s\nIt expires in %s.", token, EmailVerificationTTL),
+	})
 	return nil
 }
 
-func (s *serviceUser) UpdateUser(ctx context.Context, dto *UpdateUserDTO) (*User, error) {
-	existingUser, err := s.GetUserByEmail(ctx, dto.Email)
-	if err == nil {
-		if existingUser.ID != dto.ID {
-			return nil, customError.BusyUpdateEmailError
+// VerifyEmail marks the account the token was issued for as verified
+func (s *serviceUser) VerifyEmail(ctx context.Context, token string) error {
+	ev, err := s.storage.GetEmailVerificationByTokenHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.InvalidConfirmationTokenError
 		}
+		return err
 	}
+	if ev.Used || time.Now().UTC().After(ev.ExpiresAt) {
+		return customError.InvalidConfirmationTokenError
+	}
+	return s.storage.ApplyEmailVerification(ev)
+}
+
+// ResendVerification sends a new verification token to an unverified user
+func (s *serviceUser) ResendVerification(ctx context.Context, id int64) error {
+	u, err := s.storage.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if u.Verified {
+		return customError.AlreadyVerifiedError
+	}
+	return s.sendVerification(u)
+}
+
+// UpdateUser changes the settings of the authenticated user, dto.ID must come from the token.
+// A new password or email requires the current password, a new email is applied only after
+// it is confirmed with the token sent to it.
+func (s *serviceUser) UpdateUser(ctx context.Context, dto *UpdateUserDTO) (*UpdateUserResponseDTO, error) {
 	if err := userUpdateValidator(dto); err != nil {
 		return nil, err
 	}
-	b := dto.Password
-	if b != "" {
+	existingUser, err := s.storage.GetUserById(dto.ID)
+	if err != nil {
+		return nil, err
+	}
+	dto.Email = strings.TrimSpace(dto.Email)
+	emailChanged := dto.Email != "" && dto.Email != existingUser.Email
+	if emailChanged && !validEmail(dto.Email) {
+		return nil, customError.InvalidEmailError
+	}
+	if dto.Password != "" || emailChanged {
+		if checkPassword([]byte(existingUser.HashedPassword), []byte(dto.CurrentPassword)) != nil {
+			return nil, customError.WrongPasswordError
+		}
+	}
+	if emailChanged {
+		if _, err := s.storage.GetUserByEmail(dto.Email); err == nil {
+			return nil, customError.BusyUpdateEmailError
+		}
+	}
+	if dto.Password != "" {
 		p, err := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
 		if err != nil {
 			return nil, customError.UpdateUserBadInputError
-		} else {
-			dto.Password = string(p)
 		}
+		dto.Password = string(p)
 	}
 	u := &User{
 		ID:             dto.ID,
-		Email:          dto.Email,
 		Username:       dto.Username,
 		HashedPassword: dto.Password,
 		PhoneNumber:    dto.PhoneNumber,
 		BirthDate:      dto.BirthDate,
 	}
 	err = s.storage.UpdateUser(u)
+	if err != nil && !(emailChanged && errors.Is(err, customError.NothingToUpdateError)) {
+		return nil, err
+	}
+	response := &UpdateUserResponseDTO{}
+	if emailChanged {
+		if err := s.requestEmailChange(ctx, dto.ID, dto.Email); err != nil {
+			return nil, err
+		}
+		response.PendingEmail = dto.Email
+	}
+	updated, err := s.storage.GetUserById(dto.ID)
 	if err != nil {
 		return nil, err
 	}
-	userUpdater(u, dto)
-	return u, nil
+	response.ProfileDTO = *NewProfileDTO(updated)
+	return response, nil
 }
 
-func (s *serviceUser) Login(ctx context.Context, dto *CreateUserDTO) (*LoginResponseDTO, error) {
+// requestEmailChange stores a pending email change and sends the confirmation token to the new address in the background,
+// a mail failure must not fail an update whose other fields are already saved
+func (s *serviceUser) requestEmailChange(ctx context.Context, userID int64, email string) error {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	ec := &EmailChange{
+		UserID:    userID,
+		NewEmail:  email,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(EmailChangeTTL),
+	}
+	if err := s.storage.CreateEmailChange(ec); err != nil {
+		return err
+	}
+	s.sendMailAsync(&mail.Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body:    fmt.Sprintf("Use this token to confirm your new email address: %s\nIt expires in %s.", token, EmailChangeTTL),
+	})
+	return nil
+}
+
+// ConfirmEmailChange applies the pending email change the token was issued for
+func (s *serviceUser) ConfirmEmailChange(ctx context.Context, dto *ConfirmTokenDTO) error {
+	ec, err := s.storage.GetEmailChangeByTokenHash(utils.HashToken(dto.Token))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.InvalidConfirmationTokenError
+		}
+		return err
+	}
+	if ec.Used || time.Now().UTC().After(ec.ExpiresAt) {
+		return customError.InvalidConfirmationTokenError
+	}
+	if existing, err := s.storage.GetUserByEmail(ec.NewEmail); err == nil && existing.ID != ec.UserID {
+		return customError.BusyUpdateEmailError
+	}
+	return s.storage.ApplyEmailChange(ec)
+}
+
+// ForgotPassword emails a reset token if the address is registered. It reports success either way
+// and sends the email in the background, so the response does not reveal whether the account exists.
+func (s *serviceUser) ForgotPassword(ctx context.Context, dto *ForgotPasswordDTO) error {
+	u, err := s.storage.GetUserByEmail(strings.TrimSpace(dto.Email))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return nil
+		}
+		return err
+	}
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return err
+	}
+	pr := &PasswordReset{
+		UserID:    u.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(PasswordResetTTL),
+	}
+	if err := s.storage.CreatePasswordReset(pr); err != nil {
+		return err
+	}
+	m := &mail.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body:    fmt.Sprintf("Use this token to set a new password: %s\nIt expires in %s. If you did not ask for a reset, ignore this email.", token, PasswordResetTTL),
+	}
+	s.sendMailAsync(m)
+	return nil
+}
+
+// sendMailAsync delivers the email in the background, failures are only logged
+func (s *serviceUser) sendMailAsync(m *mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := s.mailer.Send(ctx, m); err != nil {
+			log.Printf("cannot send %q email: %v", m.Subject, err)
+		}
+	}()
+}
+
+// ResetPassword sets the new password with a reset token and ends all sessions of the user
+func (s *serviceUser) ResetPassword(ctx context.Context, dto *ResetPasswordDTO) error {
+	if dto.Password == "" {
+		return customError.UpdateUserBadInputError
+	}
+	pr, err := s.storage.GetPasswordResetByTokenHash(utils.HashToken(dto.Token))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return customError.InvalidConfirmationTokenError
+		}
+		return err
+	}
+	if pr.Used || time.Now().UTC().After(pr.ExpiresAt) {
+		return customError.InvalidConfirmationTokenError
+	}
+	p, err := bcrypt.GenerateFromPassword([]byte(dto.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return customError.UpdateUserBadInputError
+	}
+	if err := s.storage.ApplyPasswordReset(pr, string(p)); err != nil {
+		return err
+	}
+	return s.sessions.RevokeUser(ctx, pr.UserID)
+}
+
+// Login checks the password, users with two-factor authentication get a challenge instead of tokens
+func (s *serviceUser) Login(ctx context.Context, dto *CreateUserDTO) (*LoginResponseDTO, *LoginChallengeDTO, error) {
 	u, err := s.getUserPasswordByEmail(ctx, dto.Email)
 	if err != nil {
 		if errors.Is(err, customError.NotFoundError) {
-			return nil, customError.LoginError
+			return nil, nil, customError.LoginError
 		}
+		return nil, nil, err
 	}
 	if checkPassword([]byte(u.HashedPassword), []byte(dto.Password)) != nil {
-		return nil, customError.LoginError
+		return nil, nil, customError.LoginError
+	}
+	if u.Blocked {
+		return nil, nil, customError.UserBlockedError
 	}
-	token, err := generateToken(u.ID, u.Role)
+	if u.TwoFactor {
+		c, err := s.startLoginChallenge(u.ID)
+		return nil, c, err
+	}
+	sess, refreshToken, err := s.sessions.Start(ctx, u.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	token, err := s.tokenPair(u.ID, u.Role, sess, refreshToken)
+	return token, nil, err
+}
+
+// Refresh rotates the refresh token and mints a new access token with the current role
+func (s *serviceUser) Refresh(ctx context.Context, refreshToken string) (*LoginResponseDTO, error) {
+	sess, newRefreshToken, err := s.sessions.Rotate(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.storage.GetUserById(sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Blocked {
+		return nil, customError.UserBlockedError
+	}
+	return s.tokenPair(u.ID, u.Role, sess, newRefreshToken)
+}
+
+// Logout revokes the session the refresh token belongs to
+func (s *serviceUser) Logout(ctx context.Context, refreshToken string) error {
+	return s.sessions.Revoke(ctx, refreshToken)
+}
+
+func (s *serviceUser) tokenPair(id int64, role string, sess *session.Session, refreshToken string) (*LoginResponseDTO, error) {
+	token, err := generateToken(id, role, sess.FamilyID)
 	if err != nil {
 		return nil, errors.New("internal server error")
 	}
-	return &LoginResponseDTO{Token: token}, nil
+	return &LoginResponseDTO{Token: token, RefreshToken: refreshToken, RefreshExpiresAt: sess.ExpiresAt}, nil
 }
 
 func (s *serviceUser) getUserPasswordByEmail(ctx context.Context, email string) (*AuthDTO, error) {
@@ -121,14 +424,26 @@ func (s *serviceUser) getUserPasswordByEmail(ctx context.Context, email string)
 
 func dtoCreateValidator(dto *CreateUserDTO) (*User, error) {
 	u := &User{}
-	if dto.Email == "" || dto.Password == "" {
+	email := strings.TrimSpace(dto.Email)
+	if !validEmail(email) || dto.Password == "" {
 		return nil, customError.CreateUserBadInputError
 	}
-	u.Email = dto.Email
+	u.Email = email
 	u.HashedPassword = dto.Password
 	return u, nil
 }
 
+// validEmail accepts a bare address (no display name) with a dotted domain
+func validEmail(email string) bool {
+	addr, err := netMail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return false
+	}
+	at := strings.LastIndex(email, "@")
+	domain := email[at+1:]
+	return at > 0 && strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
 func userUpdateValidator(dto *UpdateUserDTO) error {
 	if dto.Username == "" && dto.PhoneNumber == "" && dto.BirthDate == "" && dto.Password == "" && dto.Email == "" {
 		return customError.NothingToUpdateError
@@ -136,30 +451,6 @@ func userUpdateValidator(dto *UpdateUserDTO) error {
 	return nil
 }
 
-func userUpdater(u *User, dto *UpdateUserDTO) (count int) {
-	if dto.Email != "" && u.Email != dto.Email {
-		u.Email = dto.Email
-		count++
-	}
-	if dto.Username != "" && u.Username != dto.Username {
-		u.Username = dto.Username
-		count++
-	}
-	if dto.Password != "" && u.HashedPassword != dto.Password {
-		u.HashedPassword = dto.Password
-		count++
-	}
-	if dto.BirthDate != "" && u.BirthDate != dto.BirthDate {
-		u.BirthDate = dto.BirthDate
-		count++
-	}
-	if dto.PhoneNumber != "" && u.PhoneNumber != dto.PhoneNumber {
-		u.PhoneNumber = dto.PhoneNumber
-		count++
-	}
-	return
-}
-
 func checkPassword(hashedPassword, password []byte) error {
 	err := bcrypt.CompareHashAndPassword(hashedPassword, password)
 	if err != nil {
@@ -168,12 +459,13 @@ func checkPassword(hashedPassword, password []byte) error {
 	return nil
 }
 
-func generateToken(id int64, role string) (string, error) {
+func generateToken(id int64, role, sessionID string) (string, error) {
 	claims := &midlleware.Claims{
-		UserID: id,
-		Role:   role,
+		UserID:    id,
+		Role:      role,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * 24).Unix(),
+			ExpiresAt: time.Now().Add(AccessTokenTTL).Unix(),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
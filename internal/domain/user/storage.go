@@ -6,4 +6,25 @@ type StorageUser interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserById(id int64) (*User, error)
 	GetUserPasswordByEmail(email string) (u *AuthDTO, err error)
+	ListUsers(dto *ListUsersDTO) ([]*User, int64, error)
+	SetUserBlocked(id int64, blocked bool) error
+	CreateEmailChange(ec *EmailChange) error
+	GetEmailChangeByTokenHash(hash string) (*EmailChange, error)
+	ApplyEmailChange(ec *EmailChange) error
+	CreatePasswordReset(pr *PasswordReset) error
+	GetPasswordResetByTokenHash(hash string) (*PasswordReset, error)
+	ApplyPasswordReset(pr *PasswordReset, hashedPassword string) error
+	CreateEmailVerification(ev *EmailVerification) error
+	GetEmailVerificationByTokenHash(hash string) (*EmailVerification, error)
+	ApplyEmailVerification(ev *EmailVerification) error
+	GetTwoFactor(userID int64) (*TwoFactor, error)
+	SetTwoFactorSecret(userID int64, secret string) error
+	EnableTwoFactor(userID int64, step int64, recoveryCodeHashes []string) error
+	DisableTwoFactor(userID int64) error
+	UseTwoFactorStep(userID int64, step int64) error
+	UseRecoveryCode(userID int64, codeHash string) error
+	CreateLoginChallenge(c *LoginChallenge) error
+	GetLoginChallengeByTokenHash(hash string) (*LoginChallenge, error)
+	FailLoginChallenge(id int64) error
+	UseLoginChallenge(id int64) error
 }
@@ -0,0 +1,205 @@
+package user
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	TOTPIssuer           = "auth-api"
+	LoginChallengeTTL    = time.Minute * 5
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+)
+
+// EnrollTwoFactor generates a new TOTP secret for the user, it is used only after ConfirmTwoFactor
+func (s *serviceUser) EnrollTwoFactor(ctx context.Context, id int64) (*TwoFactorEnrollmentDTO, error) {
+	u, err := s.storage.GetUserById(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.TwoFactor {
+		return nil, customError.TwoFactorEnabledError
+	}
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.storage.SetTwoFactorSecret(id, secret); err != nil {
+		return nil, err
+	}
+	return &TwoFactorEnrollmentDTO{
+		Secret: secret,
+		URI:    utils.TOTPURI(TOTPIssuer, u.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication with the first code from the app
+// and returns the recovery codes, they are shown only once
+func (s *serviceUser) ConfirmTwoFactor(ctx context.Context, id int64, dto *TwoFactorCodeDTO) (*RecoveryCodesDTO, error) {
+	tf, err := s.storage.GetTwoFactor(id)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, customError.TwoFactorEnabledError
+	}
+	if tf.Secret == "" {
+		return nil, customError.TwoFactorNotEnabledError
+	}
+	step, ok := utils.ValidateTOTP(tf.Secret, dto.Code, time.Now())
+	if !ok {
+		return nil, customError.TwoFactorCodeError
+	}
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	if err := s.storage.EnableTwoFactor(id, step, hashes); err != nil {
+		return nil, err
+	}
+	return &RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off, it needs both the password and a code
+func (s *serviceUser) DisableTwoFactor(ctx context.Context, id int64, dto *DisableTwoFactorDTO) error {
+	u, err := s.storage.GetUserById(id)
+	if err != nil {
+		return err
+	}
+	if checkPassword([]byte(u.HashedPassword), []byte(dto.Password)) != nil {
+		return customError.WrongPasswordError
+	}
+	tf, err := s.storage.GetTwoFactor(id)
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled {
+		return customError.TwoFactorNotEnabledError
+	}
+	if err := s.checkTwoFactor(tf, dto.Code, ""); err != nil {
+		return err
+	}
+	return s.storage.DisableTwoFactor(id)
+}
+
+// LoginTwoFactor completes a login challenge with a TOTP code or a recovery code
+func (s *serviceUser) LoginTwoFactor(ctx context.Context, dto *TwoFactorLoginDTO) (*LoginResponseDTO, error) {
+	c, err := s.storage.GetLoginChallengeByTokenHash(utils.HashToken(dto.ChallengeToken))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return nil, customError.InvalidConfirmationTokenError
+		}
+		return nil, err
+	}
+	if c.Used || c.Attempts >= maxChallengeAttempts || time.Now().UTC().After(c.ExpiresAt) {
+		return nil, customError.InvalidConfirmationTokenError
+	}
+	tf, err := s.storage.GetTwoFactor(c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTwoFactor(tf, dto.Code, dto.RecoveryCode); err != nil {
+		if errors.Is(err, customError.TwoFactorCodeError) {
+			if err := s.storage.FailLoginChallenge(c.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+	if err := s.storage.UseLoginChallenge(c.ID); err != nil {
+		return nil, err
+	}
+	u, err := s.storage.GetUserById(c.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Blocked {
+		return nil, customError.UserBlockedError
+	}
+	sess, refreshToken, err := s.sessions.Start(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tokenPair(u.ID, u.Role, sess, refreshToken)
+}
+
+// ChallengeAccount returns the normalized email of the user a login challenge was issued for,
+// the second login step is throttled by the same account key as the first
+func (s *serviceUser) ChallengeAccount(ctx context.Context, challengeToken string) (string, error) {
+	c, err := s.storage.GetLoginChallengeByTokenHash(utils.HashToken(challengeToken))
+	if err != nil {
+		if errors.Is(err, customError.NotFoundError) {
+			return "", customError.InvalidConfirmationTokenError
+		}
+		return "", err
+	}
+	u, err := s.storage.GetUserById(c.UserID)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(u.Email)), nil
+}
+
+// startLoginChallenge issues the token the second login step is bound to
+func (s *serviceUser) startLoginChallenge(userID int64) (*LoginChallengeDTO, error) {
+	token, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	c := &LoginChallenge{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().UTC().Add(LoginChallengeTTL),
+	}
+	if err := s.storage.CreateLoginChallenge(c); err != nil {
+		return nil, err
+	}
+	return &LoginChallengeDTO{TwoFactorRequired: true, ChallengeToken: token, ExpiresAt: c.ExpiresAt}, nil
+}
+
+// checkTwoFactor accepts an unused TOTP code or an unused recovery code, both are single use
+func (s *serviceUser) checkTwoFactor(tf *TwoFactor, code, recoveryCode string) error {
+	if !tf.Enabled {
+		return customError.TwoFactorNotEnabledError
+	}
+	if code != "" {
+		step, ok := utils.ValidateTOTP(tf.Secret, code, time.Now())
+		if !ok {
+			return customError.TwoFactorCodeError
+		}
+		return s.storage.UseTwoFactorStep(tf.UserID, step)
+	}
+	if recoveryCode != "" {
+		return s.storage.UseRecoveryCode(tf.UserID, hashRecoveryCode(recoveryCode))
+	}
+	return customError.TwoFactorCodeError
+}
+
+// newRecoveryCode returns a code like "1f3a9-c07d2", easy to write down
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	h := hex.EncodeToString(b)
+	return h[:5] + "-" + h[5:], nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes the user may type differently
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return utils.HashToken(code)
+}
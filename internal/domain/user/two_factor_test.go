@@ -0,0 +1,71 @@
+package user
+
+import (
+	customError "auth-api/internal/error"
+	"auth-api/internal/utils"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stepStorage keeps the last accepted TOTP step like the users.totp_last_step column,
+// the other methods of StorageUser are not used by checkTwoFactor
+type stepStorage struct {
+	StorageUser
+	lastStep int64
+}
+
+func (s *stepStorage) UseTwoFactorStep(userID int64, step int64) error {
+	if step <= s.lastStep {
+		return customError.TwoFactorCodeError
+	}
+	s.lastStep = step
+	return nil
+}
+
+func TestCheckTwoFactorReplay(t *testing.T) {
+	secret, err := utils.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := utils.TOTPStep(time.Now())
+	code := func(step int64) string {
+		c, err := utils.TOTPCode(secret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	storage := &stepStorage{}
+	s := &serviceUser{storage: storage}
+	tf := &TwoFactor{UserID: 1, Secret: secret, Enabled: true}
+
+	tests := []struct {
+		name string
+		code string
+		err  error
+	}{
+		{"previous step", code(step - 1), nil},
+		{"same code again", code(step - 1), customError.TwoFactorCodeError},
+		{"current step", code(step), nil},
+		{"older step after a newer one", code(step - 1), customError.TwoFactorCodeError},
+		{"current step again", code(step), customError.TwoFactorCodeError},
+		{"wrong code", "000000", customError.TwoFactorCodeError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.checkTwoFactor(tf, tt.code, ""); !errors.Is(err, tt.err) {
+				t.Errorf("checkTwoFactor = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckTwoFactorNotEnabled(t *testing.T) {
+	s := &serviceUser{storage: &stepStorage{}}
+	err := s.checkTwoFactor(&TwoFactor{UserID: 1}, "123456", "")
+	if !errors.Is(err, customError.TwoFactorNotEnabledError) {
+		t.Errorf("checkTwoFactor = %v, want %v", err, customError.TwoFactorNotEnabledError)
+	}
+}
@@ -3,21 +3,85 @@ package error
 import "errors"
 
 const (
-	LoginUserErrorMsg           = "invalid email or password"
-	CreateUserBadInputErrorMsg  = "invalid registration data"
-	UpdateUserBadInputErrorMsg  = "invalid update data"
-	NothingToUpdateUserErrorMsg = "nothing to update"
-	BusyUpdateEmailErrorMsg     = "email is busy"
-	UserNotFoundErrorMsg        = "not found"
-	BoxFullErrorMsg             = "recycle box is full"
+	LoginUserErrorMsg                 = "invalid email or password"
+	CreateUserBadInputErrorMsg        = "invalid registration data"
+	UpdateUserBadInputErrorMsg        = "invalid update data"
+	NothingToUpdateUserErrorMsg       = "nothing to update"
+	BusyUpdateEmailErrorMsg           = "email is busy"
+	UserNotFoundErrorMsg              = "not found"
+	BoxFullErrorMsg                   = "recycle box is full"
+	InvalidRefreshTokenErrorMsg       = "invalid refresh token"
+	RefreshTokenReuseErrorMsg         = "refresh token reuse detected"
+	InvalidListQueryErrorMsg          = "invalid list parameters"
+	InvalidLocationErrorMsg           = "invalid coordinates"
+	BoxEmptyErrorMsg                  = "recycle box is empty"
+	UnknownRoleErrorMsg               = "unknown role"
+	UserBlockedErrorMsg               = "account is blocked"
+	WrongPasswordErrorMsg             = "current password is incorrect"
+	InvalidConfirmationTokenErrorMsg  = "invalid or expired token"
+	InvalidEmailErrorMsg              = "invalid email"
+	AlreadyVerifiedErrorMsg           = "email is already verified"
+	UserNotVerifiedErrorMsg           = "email is not verified"
+	TwoFactorCodeErrorMsg             = "invalid two-factor code"
+	TwoFactorEnabledErrorMsg          = "two-factor authentication is already enabled"
+	TwoFactorNotEnabledErrorMsg       = "two-factor authentication is not enabled"
+	TwoFactorRequiredErrorMsg         = "two-factor authentication is required"
+	TooManyAttemptsErrorMsg           = "too many attempts"
+	InvalidDeviceSignatureErrorMsg    = "invalid device signature"
+	DeviceReplayErrorMsg              = "device request already used"
+	DepositSessionUnavailableErrorMsg = "deposit session is not available"
+	InvalidMaterialErrorMsg           = "invalid material"
+	MaterialExistsErrorMsg            = "material already exists"
+	MaterialNotAcceptedErrorMsg       = "material is not accepted by the recycle box"
+	InvalidRuleErrorMsg               = "invalid point rule"
+	InvalidRewardErrorMsg             = "invalid reward"
+	OutOfStockErrorMsg                = "reward is out of stock"
+	InsufficientPointsErrorMsg        = "not enough points"
+	RedemptionConsumedErrorMsg        = "redemption code is already used"
+	InvalidBadgeErrorMsg              = "invalid badge"
+	BadgeExistsErrorMsg               = "badge already exists"
+	InvalidReferralCodeErrorMsg       = "invalid referral code"
+	SelfReferralErrorMsg              = "cannot use your own referral code"
 )
 
 var (
-	NotFoundError           = errors.New(UserNotFoundErrorMsg)
-	NothingToUpdateError    = errors.New(NothingToUpdateUserErrorMsg)
-	LoginError              = errors.New(LoginUserErrorMsg)
-	BusyUpdateEmailError    = errors.New(BusyUpdateEmailErrorMsg)
-	CreateUserBadInputError = errors.New(CreateUserBadInputErrorMsg)
-	UpdateUserBadInputError = errors.New(UpdateUserBadInputErrorMsg)
-	BoxFullError            = errors.New(BoxFullErrorMsg) // Новая ошибка для полной корзины
+	NotFoundError                  = errors.New(UserNotFoundErrorMsg)
+	NothingToUpdateError           = errors.New(NothingToUpdateUserErrorMsg)
+	LoginError                     = errors.New(LoginUserErrorMsg)
+	BusyUpdateEmailError           = errors.New(BusyUpdateEmailErrorMsg)
+	CreateUserBadInputError        = errors.New(CreateUserBadInputErrorMsg)
+	UpdateUserBadInputError        = errors.New(UpdateUserBadInputErrorMsg)
+	BoxFullError                   = errors.New(BoxFullErrorMsg) // Новая ошибка для полной корзины
+	InvalidRefreshTokenError       = errors.New(InvalidRefreshTokenErrorMsg)
+	RefreshTokenReuseError         = errors.New(RefreshTokenReuseErrorMsg)
+	InvalidListQueryError          = errors.New(InvalidListQueryErrorMsg)
+	InvalidLocationError           = errors.New(InvalidLocationErrorMsg)
+	BoxEmptyError                  = errors.New(BoxEmptyErrorMsg)
+	UnknownRoleError               = errors.New(UnknownRoleErrorMsg)
+	UserBlockedError               = errors.New(UserBlockedErrorMsg)
+	WrongPasswordError             = errors.New(WrongPasswordErrorMsg)
+	InvalidConfirmationTokenError  = errors.New(InvalidConfirmationTokenErrorMsg)
+	InvalidEmailError              = errors.New(InvalidEmailErrorMsg)
+	AlreadyVerifiedError           = errors.New(AlreadyVerifiedErrorMsg)
+	UserNotVerifiedError           = errors.New(UserNotVerifiedErrorMsg)
+	TwoFactorCodeError             = errors.New(TwoFactorCodeErrorMsg)
+	TwoFactorEnabledError          = errors.New(TwoFactorEnabledErrorMsg)
+	TwoFactorNotEnabledError       = errors.New(TwoFactorNotEnabledErrorMsg)
+	TwoFactorRequiredError         = errors.New(TwoFactorRequiredErrorMsg)
+	TooManyAttemptsError           = errors.New(TooManyAttemptsErrorMsg)
+	InvalidDeviceSignatureError    = errors.New(InvalidDeviceSignatureErrorMsg)
+	DeviceReplayError              = errors.New(DeviceReplayErrorMsg)
+	DepositSessionUnavailableError = errors.New(DepositSessionUnavailableErrorMsg)
+	InvalidMaterialError           = errors.New(InvalidMaterialErrorMsg)
+	MaterialExistsError            = errors.New(MaterialExistsErrorMsg)
+	MaterialNotAcceptedError       = errors.New(MaterialNotAcceptedErrorMsg)
+	InvalidRuleError               = errors.New(InvalidRuleErrorMsg)
+	InvalidRewardError             = errors.New(InvalidRewardErrorMsg)
+	OutOfStockError                = errors.New(OutOfStockErrorMsg)
+	InsufficientPointsError        = errors.New(InsufficientPointsErrorMsg)
+	RedemptionConsumedError        = errors.New(RedemptionConsumedErrorMsg)
+	InvalidBadgeError              = errors.New(InvalidBadgeErrorMsg)
+	BadgeExistsError               = errors.New(BadgeExistsErrorMsg)
+	InvalidReferralCodeError       = errors.New(InvalidReferralCodeErrorMsg)
+	SelfReferralError              = errors.New(SelfReferralErrorMsg)
 )
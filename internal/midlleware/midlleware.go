@@ -1,25 +1,147 @@
 package midlleware
 
 import (
+	customError "auth-api/internal/error"
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
 var secretKey = []byte(os.Getenv("SECRET_KEY"))
 
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Role   string `json:"role"` // Добавляем роль в токен
+	UserID    int64  `json:"user_id"`
+	Role      string `json:"role"` // Добавляем роль в токен
+	SessionID string `json:"sid"`
 	jwt.StandardClaims
 }
 
+// RevocationList tells whether access tokens of a session must be refused before they expire
+type RevocationList interface {
+	IsRevoked(ctx context.Context, sessionID string) bool
+}
+
+var revocationList RevocationList
+
+const (
+	TokenSourceHeader = "header"
+	TokenSourceCookie = "cookie"
+	authRealm         = "auth-api"
+)
+
+var (
+	errTokenNotFound   = errors.New("Token not found")
+	errInvalidToken    = errors.New("Invalid token")
+	errTokenRevoked    = errors.New("Token revoked")
+	errMalformedHeader = errors.New("Malformed Authorization header")
+	errAccountBlocked  = errors.New("Account is blocked")
+	errUserLookup      = errors.New("Internal server error")
+)
+
+// tokenSources is the order in which parseToken looks for the access token
+var tokenSources = []string{TokenSourceHeader, TokenSourceCookie}
+
+// SetTokenPrecedence configures where the access token is looked up and in which order
+func SetTokenPrecedence(sources []string) error {
+	if len(sources) == 0 {
+		return nil
+	}
+	for _, v := range sources {
+		if v != TokenSourceHeader && v != TokenSourceCookie {
+			return fmt.Errorf("unknown token source %q", v)
+		}
+	}
+	tokenSources = sources
+	return nil
+}
+
+// trustedProxyHops is the number of proxies in front of the server that append to X-Forwarded-For
+var trustedProxyHops int
+
+// SetTrustedProxyHops makes ClientIP read the client address from X-Forwarded-For,
+// 0 keeps the peer address of the connection
+func SetTrustedProxyHops(hops int) error {
+	if hops < 0 {
+		return fmt.Errorf("invalid trusted proxy hops %d", hops)
+	}
+	trustedProxyHops = hops
+	return nil
+}
+
+// ClientIP returns the address of the client. Behind trusted proxies it is the entry of X-Forwarded-For
+// the outermost of them appended, entries left of it can be set by the client and are ignored.
+func ClientIP(r *http.Request) string {
+	if trustedProxyHops > 0 {
+		var hops []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, v := range strings.Split(header, ",") {
+				hops = append(hops, strings.TrimSpace(v))
+			}
+		}
+		if len(hops) >= trustedProxyHops {
+			if ip := net.ParseIP(hops[len(hops)-trustedProxyHops]); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SetRevocationList enables the revocation check in parseToken
+func SetRevocationList(l RevocationList) {
+	revocationList = l
+}
+
+// PermissionChecker resolves the current role of a user and the permissions it grants
+type PermissionChecker interface {
+	UserRole(ctx context.Context, userID int64) (string, bool, error)
+	RoleHasPermissions(ctx context.Context, role string, permissions ...string) (bool, error)
+	TwoFactorMissing(ctx context.Context, userID int64, role string) (bool, error)
+}
+
+var permissionChecker PermissionChecker
+
+// SetPermissionChecker makes parseToken replace the role from the token with the current one
+// and enables RequirePermission
+func SetPermissionChecker(c PermissionChecker) {
+	permissionChecker = c
+}
+
+// DeviceVerifier checks the signature of requests sent by recycle box hardware
+type DeviceVerifier interface {
+	VerifyDeviceRequest(ctx context.Context, boxID int64, method, uri, timestamp, nonce, signature string, body []byte) error
+}
+
+var deviceVerifier DeviceVerifier
+
+// Headers of signed device requests
+const (
+	DeviceBoxIDHeader     = "X-Box-Id"
+	DeviceTimestampHeader = "X-Timestamp"
+	DeviceNonceHeader     = "X-Nonce"
+	DeviceSignatureHeader = "X-Signature"
+	maxDeviceBodySize     = 1 << 20
+)
+
+// SetDeviceVerifier enables DeviceMiddleware
+func SetDeviceVerifier(v DeviceVerifier) {
+	deviceVerifier = v
+}
+
 func LoggerRequestMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Request: %s %s\n", r.Method, r.URL.Path)
@@ -51,7 +173,7 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		claims, err := parseToken(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			refuse(w, err)
 			return
 		}
 
@@ -61,47 +183,165 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// AdminMiddleware ensures that only admin users can access certain routes
-func AdminMiddleware(next http.Handler) http.Handler {
-	log.Println("admin middleware")
+// RequirePermission lets through only users whose current role grants all the permissions
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := parseToken(r)
+			if err != nil {
+				refuse(w, err)
+				return
+			}
+			if permissionChecker == nil {
+				http.Error(w, "Access denied", http.StatusForbidden)
+				return
+			}
+			allowed, err := permissionChecker.RoleHasPermissions(r.Context(), claims.Role, permissions...)
+			if err != nil {
+				log.Println(err.Error())
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
+				http.Error(w, "Access denied", http.StatusForbidden)
+				return
+			}
+			// Roles with mandatory two-factor authentication keep only the routes needed to enroll
+			missing, err := permissionChecker.TwoFactorMissing(r.Context(), claims.UserID, claims.Role)
+			if err != nil {
+				log.Println(err.Error())
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if missing {
+				http.Error(w, "Two-factor authentication required", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), "userClaims", claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// DeviceMiddleware lets through only requests signed by the hardware of a recycle box,
+// the ID of the box is put in the context as "deviceBoxID"
+func DeviceMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims, err := parseToken(r)
+		boxID, err := strconv.ParseInt(r.Header.Get(DeviceBoxIDHeader), 10, 64)
+		if err != nil || deviceVerifier == nil {
+			http.Error(w, "Device authentication required", http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDeviceBodySize))
 		if err != nil {
-			log.Println(err.Error())
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
 			return
 		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
 
-		// Check if user role is admin
-		if claims.Role != "admin" {
-			http.Error(w, "Access denied: admin only", http.StatusForbidden)
+		err = deviceVerifier.VerifyDeviceRequest(r.Context(), boxID, r.Method, r.URL.RequestURI(),
+			r.Header.Get(DeviceTimestampHeader), r.Header.Get(DeviceNonceHeader), r.Header.Get(DeviceSignatureHeader), body)
+		if err != nil {
+			if errors.Is(err, customError.InvalidDeviceSignatureError) {
+				http.Error(w, "Invalid device signature", http.StatusUnauthorized)
+			} else if errors.Is(err, customError.DeviceReplayError) {
+				http.Error(w, "Request already used", http.StatusUnauthorized)
+			} else {
+				log.Println(err.Error())
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			}
 			return
 		}
 
-		// Adding claims to context for downstream handlers
-		ctx := context.WithValue(r.Context(), "userClaims", claims)
+		ctx := context.WithValue(r.Context(), "deviceBoxID", boxID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// refuse answers a request parseToken rejected: blocked accounts get 403,
+// a failed lookup of the user 500 and every token error 401
+func refuse(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAccountBlocked) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else if errors.Is(err, errUserLookup) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		unauthorized(w, err)
+	}
+}
+
+// unauthorized writes a 401 response with a WWW-Authenticate challenge
+func unauthorized(w http.ResponseWriter, err error) {
+	challenge := fmt.Sprintf(`Bearer realm="%s"`, authRealm)
+	if !errors.Is(err, errTokenNotFound) {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description="%s"`, err.Error())
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, err.Error(), http.StatusUnauthorized)
+}
+
+// extractToken returns the access token from the first configured source that carries one
+func extractToken(r *http.Request) (string, error) {
+	for _, source := range tokenSources {
+		switch source {
+		case TokenSourceHeader:
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				continue
+			}
+			scheme, token, found := strings.Cut(header, " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				return "", errMalformedHeader
+			}
+			return strings.TrimSpace(token), nil
+		case TokenSourceCookie:
+			cookie, err := r.Cookie("token")
+			if err != nil || cookie.Value == "" {
+				continue
+			}
+			return cookie.Value, nil
+		}
+	}
+	return "", errTokenNotFound
+}
+
 // parseToken validates JWT token and returns Claims
 func parseToken(r *http.Request) (*Claims, error) {
-	cookie, err := r.Cookie("token")
+	tokenString, err := extractToken(r)
 	if err != nil {
-		if errors.Is(err, http.ErrNoCookie) {
-			return nil, errors.New("Token not found")
-		}
-		return nil, errors.New("Internal server error")
+		return nil, err
 	}
-
-	tokenString := cookie.Value
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errInvalidToken
+		}
 		return secretKey, nil
 	})
 	if err != nil || !token.Valid {
-		return nil, errors.New("Invalid token")
+		return nil, errInvalidToken
+	}
+	if revocationList != nil {
+		if claims.SessionID == "" || revocationList.IsRevoked(r.Context(), claims.SessionID) {
+			return nil, errTokenRevoked
+		}
+	}
+	if permissionChecker != nil {
+		// The role in the token may be stale, a role change must apply before the token expires
+		role, blocked, err := permissionChecker.UserRole(r.Context(), claims.UserID)
+		if err != nil {
+			if errors.Is(err, customError.NotFoundError) {
+				return nil, errInvalidToken
+			}
+			log.Println(err.Error())
+			return nil, errUserLookup
+		}
+		if blocked {
+			return nil, errAccountBlocked
+		}
+		claims.Role = role
 	}
 
 	return claims, nil
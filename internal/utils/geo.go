@@ -0,0 +1,20 @@
+package utils
+
+import "math"
+
+const EarthRadius = 6371000.0 // meters
+
+// Distance returns the great-circle distance in meters between two points (haversine formula)
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Finite rejects NaN, which passes every range check, and the infinities
+func Finite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
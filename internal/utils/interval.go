@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Interval spaces out work done on the request path, like purging expired rows, to at most once per Every
+type Interval struct {
+	Every time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Due reports whether Every has passed since the last due time, if so now becomes the last due time
+func (i *Interval) Due(now time.Time) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if now.Sub(i.last) < i.Every {
+		return false
+	}
+	i.last = now
+	return true
+}
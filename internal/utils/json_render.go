@@ -35,3 +35,32 @@ func SetCookie(w http.ResponseWriter, v string) {
 	}
 	http.SetCookie(w, &cookie)
 }
+
+func SetRefreshCookie(w http.ResponseWriter, v string, expires time.Time) {
+	cookie := http.Cookie{
+		Name:     "refresh_token",
+		Value:    v,
+		Expires:  expires,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+		HttpOnly: true,
+		Path:     "/",
+	}
+	http.SetCookie(w, &cookie)
+}
+
+// ClearCookies removes the access and refresh token cookies
+func ClearCookies(w http.ResponseWriter) {
+	for _, name := range []string{"token", "refresh_token"} {
+		cookie := http.Cookie{
+			Name:     name,
+			Value:    "",
+			MaxAge:   -1,
+			SameSite: http.SameSiteNoneMode,
+			Secure:   true,
+			HttpOnly: true,
+			Path:     "/",
+		}
+		http.SetCookie(w, &cookie)
+	}
+}
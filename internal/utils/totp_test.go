@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of the RFC 6238 test vectors, "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The RFC lists 8 digit codes, a 6 digit code is the last 6 digits of the same value
+func TestTOTPCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.code {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestTOTPCodeLowerCaseSecret(t *testing.T) {
+	got, err := TOTPCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "287082" {
+		t.Errorf("got %s, want 287082", got)
+	}
+}
+
+func TestTOTPCodeInvalidSecret(t *testing.T) {
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("expected an error for a secret that is not base32")
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+	tests := []struct {
+		name   string
+		offset int64
+		valid  bool
+	}{
+		{"current step", 0, true},
+		{"previous step", -1, true},
+		{"next step", 1, true},
+		{"two steps behind", -2, false},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := TOTPCode(rfc6238Secret, step+tt.offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matched, ok := ValidateTOTP(rfc6238Secret, code, now)
+			if ok != tt.valid {
+				t.Fatalf("ValidateTOTP ok = %v, want %v", ok, tt.valid)
+			}
+			// The matched step is what callers record to refuse a replay of the code
+			if ok && matched != step+tt.offset {
+				t.Errorf("matched step %d, want %d", matched, step+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPMalformedCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"surrounding spaces", " 050471 ", true},
+		{"empty", "", false},
+		{"too short", "05047", false},
+		{"eight digits", "14050471", false},
+		{"wrong code", "050472", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(rfc6238Secret, tt.code, now); ok != tt.ok {
+				t.Errorf("ValidateTOTP(%q) ok = %v, want %v", tt.code, ok, tt.ok)
+			}
+		})
+	}
+}
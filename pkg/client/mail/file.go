@@ -0,0 +1,31 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+type fileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSender returns a Sender that appends the emails to a file, for local development and tests
+func NewFileSender(path string) Sender {
+	return &fileSender{path: path}
+}
+
+func (s *fileSender) Send(ctx context.Context, m *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), m.To, m.Subject, m.Body)
+	return err
+}
@@ -0,0 +1,272 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+var ErrChecksumMismatch = errors.New("applied migration has been edited")
+
+// Migration is a numbered schema change loaded from migrations/NNNN_name.up.sql and NNNN_name.down.sql
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// MigrationStatus describes a known migration and whether it is applied to the database
+type MigrationStatus struct {
+	Migration *Migration
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func createMigrationsTable(db *sql.DB) error {
+	q := `
+CREATE TABLE IF NOT EXISTS schema_migrations(
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	applied_at DATETIME NOT NULL
+)
+`
+	_, err := db.Exec(q)
+	return err
+}
+
+// LoadMigrations reads the embedded migrations ordered by version
+func LoadMigrations() ([]*Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		num, title, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name %s", name)
+		}
+		version, err := strconv.Atoi(num)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s", name)
+		}
+		body, err := migrationFiles.ReadFile("migrations/" + name)
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+			sum := sha256.Sum256(body)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+	var migrations []*Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func loadApplied(db *sql.DB) (map[int]appliedMigration, error) {
+	if err := createMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := map[int]appliedMigration{}
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+	return applied, rows.Err()
+}
+
+// Status lists every known migration with its applied state
+func Status(db *sql.DB) ([]*MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := loadApplied(db)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []*MigrationStatus
+	for _, m := range migrations {
+		st := &MigrationStatus{Migration: m}
+		if a, ok := applied[m.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = a.appliedAt
+			st.Modified = a.checksum != m.Checksum
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Migrate applies every pending migration in order. With dryRun set it only
+// reports what would run. It refuses to run when an applied migration was edited.
+func Migrate(db *sql.DB, dryRun bool) ([]*Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Migration
+	for _, st := range statuses {
+		if st.Modified {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrChecksumMismatch, st.Migration.Version, st.Migration.Name)
+		}
+		if !st.Applied {
+			pending = append(pending, st.Migration)
+		}
+	}
+	for _, m := range pending {
+		if dryRun {
+			log.Printf("migration %04d_%s would be applied:\n%s", m.Version, m.Name, m.Up)
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return nil, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migration %04d_%s: OK", m.Version, m.Name)
+	}
+	return pending, nil
+}
+
+// Rollback reverts the last steps applied migrations
+func Rollback(db *sql.DB, steps int, dryRun bool) ([]*Migration, error) {
+	statuses, err := Status(db)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []*Migration
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		st := statuses[i]
+		if !st.Applied {
+			continue
+		}
+		m := st.Migration
+		if m.Down == "" {
+			return reverted, fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+		}
+		reverted = append(reverted, m)
+		if dryRun {
+			log.Printf("migration %04d_%s would be reverted:\n%s", m.Version, m.Name, m.Down)
+			continue
+		}
+		if err := revertMigration(db, m); err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("migration %04d_%s reverted: OK", m.Version, m.Name)
+	}
+	return reverted, nil
+}
+
+func applyMigration(db *sql.DB, m *Migration) error {
+	return withMigrationTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.Up); err != nil {
+			return err
+		}
+		q := `INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`
+		_, err := tx.Exec(q, m.Version, m.Name, m.Checksum, time.Now().UTC())
+		return err
+	})
+}
+
+func revertMigration(db *sql.DB, m *Migration) error {
+	return withMigrationTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.Version)
+		return err
+	})
+}
+
+// withMigrationTx runs fn in a transaction with foreign keys switched off on that
+// connection, so migrations can rebuild tables without cascading deletes.
+// Foreign keys are checked before commit.
+func withMigrationTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	rows, err := tx.Query(`PRAGMA foreign_key_check`)
+	if err != nil {
+		return err
+	}
+	violations := rows.Next()
+	rows.Close()
+	if violations {
+		return errors.New("foreign key check failed")
+	}
+	return tx.Commit()
+}
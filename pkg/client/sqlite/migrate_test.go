@@ -0,0 +1,125 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// openTestDB opens an empty database file with the options of config.json
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := NewDB("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_foreign_keys=on&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		t.Fatalf("NewDB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func appliedCount(t *testing.T, db *sql.DB) int {
+	t.Helper()
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	n := 0
+	for _, st := range statuses {
+		if st.Applied {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, m.Version, i+1)
+		}
+		if m.Down == "" {
+			t.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+		}
+		if m.Checksum == "" {
+			t.Errorf("migration %04d_%s has no checksum", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrateUpDownUp(t *testing.T) {
+	db := openTestDB(t)
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations: %v", err)
+	}
+
+	pending, err := Migrate(db, true)
+	if err != nil {
+		t.Fatalf("Migrate dry run: %v", err)
+	}
+	if len(pending) != len(migrations) || appliedCount(t, db) != 0 {
+		t.Fatalf("dry run: %d pending and %d applied, want %d pending and none applied", len(pending), appliedCount(t, db), len(migrations))
+	}
+
+	if _, err := Migrate(db, false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if n := appliedCount(t, db); n != len(migrations) {
+		t.Fatalf("%d migrations applied, want %d", n, len(migrations))
+	}
+	if pending, err := Migrate(db, false); err != nil || len(pending) != 0 {
+		t.Fatalf("second Migrate = %d, %v, want nothing to apply", len(pending), err)
+	}
+
+	reverted, err := Rollback(db, 1, false)
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != migrations[len(migrations)-1].Version {
+		t.Fatalf("Rollback reverted %d migrations, want the last one", len(reverted))
+	}
+
+	if _, err := Rollback(db, len(migrations), false); err != nil {
+		t.Fatalf("Rollback of every migration: %v", err)
+	}
+	if n := appliedCount(t, db); n != 0 {
+		t.Fatalf("%d migrations applied after reverting all, want none", n)
+	}
+
+	if _, err := Migrate(db, false); err != nil {
+		t.Fatalf("Migrate after reverting all: %v", err)
+	}
+	if n := appliedCount(t, db); n != len(migrations) {
+		t.Fatalf("%d migrations applied again, want %d", n, len(migrations))
+	}
+}
+
+func TestMigrateRefusesEditedMigration(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := Migrate(db, false); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE schema_migrations SET checksum = 'edited' WHERE version = 1`); err != nil {
+		t.Fatalf("edit checksum: %v", err)
+	}
+	statuses, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, st := range statuses {
+		if want := st.Migration.Version == 1; st.Modified != want {
+			t.Errorf("migration %04d_%s modified = %t, want %t", st.Migration.Version, st.Migration.Name, st.Modified, want)
+		}
+	}
+	if _, err := Migrate(db, false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Migrate with an edited migration = %v, want ErrChecksumMismatch", err)
+	}
+}
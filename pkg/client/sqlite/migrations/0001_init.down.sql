@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recycle_boxes;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS users(
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT DEFAULT '',
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	phone_number TEXT DEFAULT '',
+	birth_date DATE DEFAULT '',
+	points INTEGER DEFAULT 0,
+	role TEXT CHECK (role IN ('admin', 'user')) DEFAULT 'user'
+);
+
+CREATE TABLE IF NOT EXISTS recycle_boxes(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	address TEXT NOT NULL,
+	capacity INTEGER NOT NULL DEFAULT 10,
+	count INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS sessions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	family_id TEXT NOT NULL,
+	user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	token_hash TEXT UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used INTEGER NOT NULL DEFAULT 0,
+	revoked INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions(family_id);
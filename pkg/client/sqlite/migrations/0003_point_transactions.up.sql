@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS point_transactions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	box_id INTEGER REFERENCES recycle_boxes(id) ON DELETE SET NULL,
+	amount INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_point_transactions_user_id ON point_transactions(user_id, created_at);
+
+-- Balances earned before the ledger existed become a single opening entry
+INSERT INTO point_transactions(user_id, amount, reason)
+SELECT user_id, points, 'opening_balance' FROM users
+WHERE points <> 0 AND user_id NOT IN (SELECT user_id FROM point_transactions);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_recycle_boxes_location;
+ALTER TABLE recycle_boxes DROP COLUMN longitude;
+ALTER TABLE recycle_boxes DROP COLUMN latitude;
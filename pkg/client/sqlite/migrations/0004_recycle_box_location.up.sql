@@ -0,0 +1,4 @@
+ALTER TABLE recycle_boxes ADD COLUMN latitude REAL;
+ALTER TABLE recycle_boxes ADD COLUMN longitude REAL;
+
+CREATE INDEX IF NOT EXISTS idx_recycle_boxes_location ON recycle_boxes(latitude, longitude);
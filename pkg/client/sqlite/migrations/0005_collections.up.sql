@@ -0,0 +1,25 @@
+-- Allow the collector role, SQLite cannot alter a CHECK constraint so users is rebuilt
+CREATE TABLE users_new(
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT DEFAULT '',
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	phone_number TEXT DEFAULT '',
+	birth_date DATE DEFAULT '',
+	points INTEGER DEFAULT 0,
+	role TEXT CHECK (role IN ('admin', 'collector', 'user')) DEFAULT 'user'
+);
+INSERT INTO users_new(user_id, username, email, password, phone_number, birth_date, points, role)
+SELECT user_id, username, email, password, phone_number, birth_date, points, role FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE TABLE IF NOT EXISTS collections(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	box_id INTEGER NOT NULL REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	collector_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+	bottles INTEGER NOT NULL,
+	collected_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_collections_box_id ON collections(box_id, collected_at);
@@ -0,0 +1,18 @@
+CREATE TABLE users_old(
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT DEFAULT '',
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	phone_number TEXT DEFAULT '',
+	birth_date DATE DEFAULT '',
+	points INTEGER DEFAULT 0,
+	role TEXT CHECK (role IN ('admin', 'collector', 'user')) DEFAULT 'user'
+);
+INSERT INTO users_old(user_id, username, email, password, phone_number, birth_date, points, role)
+SELECT user_id, username, email, password, phone_number, birth_date, points,
+	CASE WHEN role IN ('admin', 'collector') THEN role ELSE 'user' END FROM users;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS roles;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS roles(
+	name TEXT PRIMARY KEY,
+	description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions(
+	role TEXT NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+	permission TEXT NOT NULL,
+	PRIMARY KEY (role, permission)
+);
+
+INSERT OR IGNORE INTO roles(name, description) VALUES
+	('admin', 'Full access'),
+	('collector', 'Empties recycle boxes'),
+	('user', 'Deposits bottles and earns points');
+
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES
+	('admin', 'box:create'),
+	('admin', 'box:update'),
+	('admin', 'box:collect'),
+	('admin', 'box:deposit'),
+	('admin', 'user:manage'),
+	('collector', 'box:collect'),
+	('collector', 'box:deposit'),
+	('user', 'box:deposit');
+
+-- Roles are now a table, replace the CHECK constraint with a foreign key
+CREATE TABLE users_new(
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT DEFAULT '',
+	email TEXT UNIQUE NOT NULL,
+	password TEXT NOT NULL,
+	phone_number TEXT DEFAULT '',
+	birth_date DATE DEFAULT '',
+	points INTEGER DEFAULT 0,
+	role TEXT NOT NULL DEFAULT 'user' REFERENCES roles(name)
+);
+INSERT INTO users_new(user_id, username, email, password, phone_number, birth_date, points, role)
+SELECT user_id, username, email, password, phone_number, birth_date, points, COALESCE(role, 'user') FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
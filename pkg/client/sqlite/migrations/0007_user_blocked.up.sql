@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN blocked INTEGER NOT NULL DEFAULT 0;
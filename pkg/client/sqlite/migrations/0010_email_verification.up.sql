@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN verified INTEGER NOT NULL DEFAULT 0;
+
+-- Accounts registered before verification existed stay usable
+UPDATE users SET verified = 1;
+
+CREATE TABLE IF NOT EXISTS email_verifications(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	token_hash TEXT UNIQUE NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+-- Failed attempt counters of /login and /register, key is "<scope>:<ip or email>"
+CREATE TABLE IF NOT EXISTS auth_attempts(
+	key TEXT PRIMARY KEY,
+	failures INTEGER NOT NULL DEFAULT 0,
+	last_failure DATETIME NOT NULL,
+	locked_until DATETIME
+);
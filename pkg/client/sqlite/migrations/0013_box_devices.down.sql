@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS device_nonces;
+DROP TABLE IF EXISTS box_devices;
@@ -0,0 +1,14 @@
+-- The secret is needed in plain text to check HMAC signatures, it is never returned after it is issued
+CREATE TABLE IF NOT EXISTS box_devices(
+	box_id INTEGER PRIMARY KEY REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	secret TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS device_nonces(
+	box_id INTEGER NOT NULL REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	nonce TEXT NOT NULL,
+	expires_at DATETIME NOT NULL,
+	PRIMARY KEY (box_id, nonce)
+);
+CREATE INDEX IF NOT EXISTS idx_device_nonces_expires_at ON device_nonces(expires_at);
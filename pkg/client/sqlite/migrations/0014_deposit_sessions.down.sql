@@ -0,0 +1 @@
+DROP TABLE IF EXISTS deposit_sessions;
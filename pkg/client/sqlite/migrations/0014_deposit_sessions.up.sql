@@ -0,0 +1,16 @@
+-- A deposit session binds the bottles a box receives to the user who claimed it.
+-- status: open (waiting for a claim), claimed, closed
+CREATE TABLE IF NOT EXISTS deposit_sessions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	box_id INTEGER NOT NULL REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+	code_hash TEXT UNIQUE NOT NULL,
+	status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'claimed', 'closed')),
+	bottles INTEGER NOT NULL DEFAULT 0,
+	points INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	claimed_at DATETIME,
+	closed_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_deposit_sessions_box_id ON deposit_sessions(box_id, status);
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'material:manage';
+DROP TABLE IF EXISTS box_materials;
+DROP TABLE IF EXISTS materials;
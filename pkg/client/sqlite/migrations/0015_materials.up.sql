@@ -0,0 +1,20 @@
+-- weight is the share of the box capacity one item takes
+CREATE TABLE IF NOT EXISTS materials(
+	code TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	points INTEGER NOT NULL CHECK (points >= 0),
+	weight INTEGER NOT NULL DEFAULT 1 CHECK (weight > 0),
+	active INTEGER NOT NULL DEFAULT 1
+);
+
+-- The default type keeps the behaviour from before material types: 100 points, one capacity unit
+INSERT OR IGNORE INTO materials(code, name, points, weight) VALUES ('bottle', 'Bottle', 100, 1);
+
+CREATE TABLE IF NOT EXISTS box_materials(
+	box_id INTEGER NOT NULL REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	material TEXT NOT NULL REFERENCES materials(code),
+	PRIMARY KEY (box_id, material)
+);
+INSERT OR IGNORE INTO box_materials(box_id, material) SELECT id, 'bottle' FROM recycle_boxes;
+
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES ('admin', 'material:manage');
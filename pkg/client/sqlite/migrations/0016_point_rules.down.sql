@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'rule:manage';
+DROP TABLE IF EXISTS point_transaction_rules;
+DROP TABLE IF EXISTS point_rules;
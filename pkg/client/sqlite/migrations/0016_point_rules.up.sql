@@ -0,0 +1,36 @@
+-- A point rule adds extra points to deposits that match its conditions.
+-- multiplier applies to the base points of the materials (2 doubles them), bonus is added per deposit.
+-- weekdays and box_ids are JSON arrays, empty arrays match every day and every box.
+CREATE TABLE IF NOT EXISTS point_rules(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	multiplier REAL NOT NULL DEFAULT 1 CHECK (multiplier >= 1),
+	bonus INTEGER NOT NULL DEFAULT 0 CHECK (bonus >= 0),
+	starts_at DATETIME,
+	ends_at DATETIME,
+	weekdays TEXT NOT NULL DEFAULT '[]',
+	box_ids TEXT NOT NULL DEFAULT '[]',
+	zone_latitude REAL,
+	zone_longitude REAL,
+	zone_radius REAL,
+	first_deposit_of_day INTEGER NOT NULL DEFAULT 0,
+	max_fill_percent INTEGER,
+	user_daily_cap INTEGER NOT NULL DEFAULT 0,
+	user_total_cap INTEGER NOT NULL DEFAULT 0,
+	priority INTEGER NOT NULL DEFAULT 0,
+	exclusive INTEGER NOT NULL DEFAULT 0,
+	active INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL
+);
+
+-- The points every rule contributed to a ledger entry
+CREATE TABLE IF NOT EXISTS point_transaction_rules(
+	transaction_id INTEGER NOT NULL REFERENCES point_transactions(id) ON DELETE CASCADE,
+	rule_id INTEGER NOT NULL REFERENCES point_rules(id),
+	points INTEGER NOT NULL,
+	PRIMARY KEY (transaction_id, rule_id)
+);
+CREATE INDEX IF NOT EXISTS idx_point_transaction_rules_rule_id ON point_transaction_rules(rule_id);
+
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES ('admin', 'rule:manage');
@@ -0,0 +1,5 @@
+DELETE FROM role_permissions WHERE permission IN ('reward:manage', 'reward:consume');
+UPDATE users SET role = 'user' WHERE role = 'partner';
+DELETE FROM roles WHERE name = 'partner';
+DROP TABLE IF EXISTS redemptions;
+DROP TABLE IF EXISTS rewards;
@@ -0,0 +1,33 @@
+-- stock NULL is unlimited, the CHECK makes overselling impossible
+CREATE TABLE IF NOT EXISTS rewards(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	title TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL CHECK (kind IN ('voucher', 'discount')),
+	partner_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+	cost INTEGER NOT NULL CHECK (cost > 0),
+	stock INTEGER CHECK (stock >= 0),
+	active INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL
+);
+
+-- status: issued (stock reserved, code not used yet), consumed
+CREATE TABLE IF NOT EXISTS redemptions(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	reward_id INTEGER NOT NULL REFERENCES rewards(id),
+	user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	transaction_id INTEGER REFERENCES point_transactions(id) ON DELETE SET NULL,
+	code TEXT UNIQUE NOT NULL,
+	cost INTEGER NOT NULL,
+	status TEXT NOT NULL DEFAULT 'issued' CHECK (status IN ('issued', 'consumed')),
+	created_at DATETIME NOT NULL,
+	consumed_at DATETIME,
+	consumed_by INTEGER REFERENCES users(user_id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_redemptions_user_id ON redemptions(user_id, created_at);
+
+INSERT OR IGNORE INTO roles(name, description) VALUES ('partner', 'Validates and consumes reward codes');
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES
+	('admin', 'reward:manage'),
+	('admin', 'reward:consume'),
+	('partner', 'reward:consume');
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS users_handle;
+DROP INDEX IF EXISTS idx_users_handle;
+ALTER TABLE users DROP COLUMN handle;
+ALTER TABLE users DROP COLUMN leaderboard_public;
+DROP TABLE IF EXISTS deposits;
@@ -0,0 +1,25 @@
+-- Every item counted by a box, user_id is NULL for anonymous deposits.
+-- Deposits made before this migration were not itemised and are not in the history.
+CREATE TABLE IF NOT EXISTS deposits(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	box_id INTEGER NOT NULL REFERENCES recycle_boxes(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(user_id) ON DELETE SET NULL,
+	session_id INTEGER REFERENCES deposit_sessions(id) ON DELETE SET NULL,
+	material TEXT NOT NULL,
+	points INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_deposits_user_id ON deposits(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_deposits_box_id ON deposits(box_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_deposits_created_at ON deposits(created_at);
+
+-- Users who opt out of the public leaderboards are shown by their handle only
+ALTER TABLE users ADD COLUMN leaderboard_public INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'recycler-' || lower(hex(randomblob(4))) WHERE handle IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_handle ON users(handle);
+
+CREATE TRIGGER IF NOT EXISTS users_handle AFTER INSERT ON users WHEN NEW.handle IS NULL
+BEGIN
+	UPDATE users SET handle = 'recycler-' || lower(hex(randomblob(4))) WHERE user_id = NEW.user_id;
+END;
@@ -0,0 +1,3 @@
+DELETE FROM role_permissions WHERE permission = 'badge:manage';
+DROP TABLE IF EXISTS user_badges;
+DROP TABLE IF EXISTS badges;
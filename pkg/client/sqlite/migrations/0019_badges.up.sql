@@ -0,0 +1,30 @@
+-- A badge is earned when a counter of the user's deposits reaches the threshold:
+-- bottles deposited, distinct boxes used or consecutive UTC days with a deposit
+CREATE TABLE IF NOT EXISTS badges(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	code TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	kind TEXT NOT NULL CHECK (kind IN ('bottles', 'boxes', 'streak')),
+	threshold INTEGER NOT NULL CHECK (threshold > 0),
+	bonus_points INTEGER NOT NULL DEFAULT 0 CHECK (bonus_points >= 0),
+	active INTEGER NOT NULL DEFAULT 1,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The primary key makes awarding idempotent, transaction_id is the ledger entry of the bonus
+CREATE TABLE IF NOT EXISTS user_badges(
+	user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	badge_id INTEGER NOT NULL REFERENCES badges(id) ON DELETE CASCADE,
+	transaction_id INTEGER REFERENCES point_transactions(id) ON DELETE SET NULL,
+	awarded_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, badge_id)
+);
+
+INSERT OR IGNORE INTO badges(code, name, description, kind, threshold, bonus_points) VALUES
+	('first_bottle', 'First bottle', 'Deposit your first bottle', 'bottles', 1, 0),
+	('bottles_100', '100 bottles', 'Deposit 100 bottles', 'bottles', 100, 500),
+	('streak_7', '7-day streak', 'Deposit something 7 days in a row', 'streak', 7, 200),
+	('boxes_5', 'Explorer', 'Use 5 different recycle boxes', 'boxes', 5, 100);
+
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES ('admin', 'badge:manage');
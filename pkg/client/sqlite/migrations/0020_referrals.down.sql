@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS referrals;
+DROP TRIGGER IF EXISTS users_invite_code;
+DROP INDEX IF EXISTS idx_users_invite_code;
+ALTER TABLE users DROP COLUMN invite_code;
@@ -0,0 +1,21 @@
+-- Every user gets a personal invite code, new users get theirs from the trigger
+ALTER TABLE users ADD COLUMN invite_code TEXT;
+UPDATE users SET invite_code = upper(hex(randomblob(4))) WHERE invite_code IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_invite_code ON users(invite_code);
+
+CREATE TRIGGER IF NOT EXISTS users_invite_code AFTER INSERT ON users WHEN NEW.invite_code IS NULL
+BEGIN
+	UPDATE users SET invite_code = upper(hex(randomblob(4))) WHERE user_id = NEW.user_id;
+END;
+
+-- A user is referred at most once. The referral is settled by the first deposit of the referee:
+-- rewarded, capped (the referrer reached the limit) or blocked (either account is blocked).
+CREATE TABLE IF NOT EXISTS referrals(
+	referee_id INTEGER PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+	referrer_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'rewarded', 'capped', 'blocked')),
+	created_at DATETIME NOT NULL,
+	settled_at DATETIME,
+	CHECK (referee_id <> referrer_id)
+);
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer_id ON referrals(referrer_id, status);
@@ -0,0 +1,7 @@
+-- The last notice about expiring points a user got, it keeps reruns of the job from repeating it
+CREATE TABLE IF NOT EXISTS point_expiry_notices(
+	user_id INTEGER PRIMARY KEY REFERENCES users(user_id) ON DELETE CASCADE,
+	amount INTEGER NOT NULL,
+	expires_before DATETIME NOT NULL,
+	notified_at DATETIME NOT NULL
+);
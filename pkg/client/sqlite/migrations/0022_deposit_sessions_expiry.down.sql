@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_deposit_sessions_expires_at;
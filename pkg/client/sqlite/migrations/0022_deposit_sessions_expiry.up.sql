@@ -0,0 +1,2 @@
+-- The points job settles the sessions that expired without the box closing them
+CREATE INDEX IF NOT EXISTS idx_deposit_sessions_expires_at ON deposit_sessions(status, expires_at);